@@ -17,6 +17,8 @@ limitations under the License.
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
 )
 
@@ -26,16 +28,252 @@ type PrivxProvider struct {
 	// Server is the connection address for the server, e.g: "https://privx.example.com:8080".
 	Host string `json:"host"`
 
+	// APIBasePath is prefixed to the paths of the PrivX API requests, e.g. "/privx"
+	// for a server published below a path. When empty, the paths start at Host.
+	// +optional
+	APIBasePath string `json:"apiBasePath,omitempty"`
+
+	// AuthPath replaces the path of the OAuth token endpoint, e.g.
+	// "/privx/auth/api/v1/oauth/token" for a reverse proxy rewriting the API paths.
+	// When empty, the standard "/auth/api/v1/oauth/token" below Host is used.
+	// +optional
+	AuthPath string `json:"authPath,omitempty"`
+
 	// Auth configures how secret-manager authenticates with PrivX server.
 	Auth *PrivXAuth `json:"auth,omitempty"`
 
+	// VaultScope selects the PrivX vault the store reads and writes:
+	// "shared" for the shared vault with role based access, or "personal"
+	// for the personal vault of VaultUserID. Defaults to "shared".
+	// +optional
+	// +kubebuilder:validation:Enum=shared;personal
+	VaultScope PrivXVaultScope `json:"vaultScope,omitempty"`
+
+	// VaultUserID is the PrivX user ID whose personal vault is used.
+	// Required with VaultScope "personal".
+	// +optional
+	VaultUserID string `json:"vaultUserID,omitempty"`
+
 	// DefaultReadRoles are used upon pushing new secrets to PrivX to set read access.
 	DefaultReadRoles []string `json:"defaultReadRoles"`
 
 	// DefaultWriteRoles are used upon pushing new secrets to PrivX to set write access.
 	DefaultWriteRoles []string `json:"defaultWriteRoles"`
+
+	// NameTemplate is a Go text/template deriving the PrivX secret name on push and delete,
+	// e.g. "{{ .Namespace }}-{{ .SecretName }}-{{ .SecretKey }}".
+	// Available variables are .Namespace, .SecretName, .SecretKey, .RemoteKey and .Property.
+	// Deletion only knows .Namespace, .RemoteKey and .Property.
+	// When empty, the remote key is used, falling back to the source secret name.
+	// +optional
+	NameTemplate string `json:"nameTemplate,omitempty"`
+
+	// ManagedOnly makes PushSecret mark the secrets it creates, and refuse to
+	// overwrite existing secrets without that mark, e.g. ones created by hand.
+	// +optional
+	ManagedOnly bool `json:"managedOnly,omitempty"`
+
+	// RequireManagedByLabel makes PushSecret mark the secrets it writes like
+	// ManagedOnly, and DeleteSecret refuse to delete a secret without the marker.
+	// PrivX secrets have no labels, so the marker is a reserved data key.
+	// +optional
+	RequireManagedByLabel bool `json:"requireManagedByLabel,omitempty"`
+
+	// CaseInsensitiveProperty matches the keys named by a property regardless of
+	// case, e.g. "password" selects a "Password" key. A property matching several
+	// keys that differ only by case is an error. Defaults to exact matching.
+	// +optional
+	CaseInsensitiveProperty bool `json:"caseInsensitiveProperty,omitempty"`
+
+	// PropertyMatch decides how a property such as "db.host" is resolved when the
+	// secret has both a literal "db.host" key and a "db" object with a "host" field:
+	// "literalFirst" selects the literal key, "pathFirst" the nested field.
+	// Defaults to "literalFirst".
+	// +optional
+	// +kubebuilder:validation:Enum=literalFirst;pathFirst
+	PropertyMatch PrivXPropertyMatch `json:"propertyMatch,omitempty"`
+
+	// PreflightRoles makes client creation check that the default read and write
+	// roles exist in PrivX and that at least one write role remains, so that a store
+	// unable to push fails immediately rather than on the first PushSecret.
+	// Ignored for personal vaults.
+	// +optional
+	PreflightRoles bool `json:"preflightRoles,omitempty"`
+
+	// RequireProperty makes data entries and dataFrom.extract without a property fail,
+	// instead of returning the whole secret. dataFrom.find is not affected.
+	// +optional
+	RequireProperty bool `json:"requireProperty,omitempty"`
+
+	// RejectObjectProperty makes a data entry whose property selects a JSON object
+	// fail with a hint to use dataFrom.extract, instead of returning the object as JSON.
+	// A list of properties still returns an object. Defaults to returning the object.
+	// +optional
+	RejectObjectProperty bool `json:"rejectObjectProperty,omitempty"`
+
+	// ValueTransform shapes the string values selected by a property before they
+	// are returned, and before any decodingStrategy is applied. Disabled when unset.
+	// +optional
+	ValueTransform *PrivXValueTransform `json:"valueTransform,omitempty"`
+
+	// FlattenSecretMap makes dataFrom.extract return the leaves of nested objects
+	// under dotted keys, e.g. {"db":{"user":"x"}} as "db.user". Arrays are returned
+	// as JSON. Defaults to returning the top-level keys only.
+	// +optional
+	FlattenSecretMap bool `json:"flattenSecretMap,omitempty"`
+
+	// KeyNormalization rewrites the keys returned by dataFrom.extract and dataFrom.find
+	// to valid Kubernetes Secret keys: "relaxed" replaces invalid characters with "_",
+	// "strict" also lowercases the keys. Keys that collide after normalization are an
+	// error. Defaults to "off", returning the keys unchanged.
+	// +optional
+	// +kubebuilder:validation:Enum=off;relaxed;strict
+	KeyNormalization PrivXKeyNormalization `json:"keyNormalization,omitempty"`
+
+	// FindMetadataOnly makes dataFrom.find return the metadata of each matching secret
+	// (name, roles, owner and timestamps) as JSON instead of its data.
+	// Secret data is then never fetched.
+	// +optional
+	FindMetadataOnly bool `json:"findMetadataOnly,omitempty"`
+
+	// ReferenceCounting lets several pushes share a PrivX secret.
+	// Each push is recorded in the secret under the "eso-refs" key by its property,
+	// and deleting a push removes the secret only when no other push refers to it.
+	// +optional
+	ReferenceCounting bool `json:"referenceCounting,omitempty"`
+
+	// VerifyAfterWrite makes PushSecret read each pushed secret back and fail
+	// when the stored value differs from the pushed one. Off by default, as it
+	// adds a read to every push.
+	// +optional
+	VerifyAfterWrite bool `json:"verifyAfterWrite,omitempty"`
+
+	// AllowedKeys are regular expressions of the PrivX secret names the store may
+	// read and write, each matching whole names. When empty, every name not denied
+	// is allowed.
+	// +optional
+	AllowedKeys []string `json:"allowedKeys,omitempty"`
+
+	// DeniedKeys are regular expressions of the PrivX secret names the store may
+	// not read or write, each matching any part of a name. Denial takes precedence
+	// over AllowedKeys.
+	// +optional
+	DeniedKeys []string `json:"deniedKeys,omitempty"`
+
+	// MaxIdleConns limits the number of idle connections kept open to PrivX.
+	// Zero means no limit. Defaults to 100.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxIdleConns *int `json:"maxIdleConns,omitempty"`
+
+	// MaxIdleConnsPerHost limits the number of idle connections kept open per PrivX host.
+	// Defaults to 10.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxIdleConnsPerHost *int `json:"maxIdleConnsPerHost,omitempty"`
+
+	// IdleConnTimeout is how long an idle connection to PrivX is kept open.
+	// Zero means no limit. Defaults to 90s.
+	// +optional
+	IdleConnTimeout *metav1.Duration `json:"idleConnTimeout,omitempty"`
+
+	// RequestTimeout limits the time a single secret operation, e.g. reading or
+	// pushing one secret, may take. Zero or unset means no limit.
+	// +optional
+	RequestTimeout *metav1.Duration `json:"requestTimeout,omitempty"`
+
+	// FindTimeout limits the time dataFrom.find may take in total, including listing
+	// the vault and fetching every matching secret. Defaults to RequestTimeout.
+	// +optional
+	FindTimeout *metav1.Duration `json:"findTimeout,omitempty"`
+
+	// MaxSecretSize limits the size in bytes of the secrets read from and pushed to
+	// PrivX. A read secret is measured by its JSON encoding, a push by the pushed values.
+	// Defaults to 1048576, the size limit of a Kubernetes Secret.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxSecretSize *int `json:"maxSecretSize,omitempty"`
+
+	// MaxFindResults limits the number of secrets dataFrom.find may return.
+	// Unlimited when unset, MaxFindBytes bounding the size of a find.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxFindResults *int `json:"maxFindResults,omitempty"`
+
+	// MaxFindBytes limits the total size in bytes of the secrets dataFrom.find may
+	// return, each measured by its JSON encoding. Defaults to 16777216.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxFindBytes *int `json:"maxFindBytes,omitempty"`
+
+	// CircuitBreaker stops calling a PrivX host for a while after consecutive
+	// failures to reach it. Disabled when unset.
+	// +optional
+	CircuitBreaker *PrivXCircuitBreaker `json:"circuitBreaker,omitempty"`
+}
+
+// PrivXValueTransform configures how values read from PrivX are shaped.
+type PrivXValueTransform struct {
+	// Trim removes leading and trailing whitespace.
+	// +optional
+	Trim bool `json:"trim,omitempty"`
+
+	// StripPrefix removes a prefix, e.g. "Bearer ", from values starting with it.
+	// With Trim, the prefix is removed after trimming.
+	// +optional
+	StripPrefix string `json:"stripPrefix,omitempty"`
+}
+
+// PrivXCircuitBreaker configures the circuit breaker of a PrivX host.
+type PrivXCircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failures that opens the
+	// circuit. Defaults to 5.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	FailureThreshold *int `json:"failureThreshold,omitempty"`
+
+	// Cooldown is how long an open circuit fails calls immediately before a
+	// single call probes PrivX again. Defaults to 30s.
+	// +optional
+	Cooldown *metav1.Duration `json:"cooldown,omitempty"`
 }
 
+// PrivXPropertyMatch selects whether a property matches a literal key or a path first.
+type PrivXPropertyMatch string
+
+const (
+	// PrivXPropertyMatchLiteralFirst prefers an exact top-level key over a path.
+	PrivXPropertyMatchLiteralFirst PrivXPropertyMatch = "literalFirst"
+
+	// PrivXPropertyMatchPathFirst prefers a path through nested objects over an exact key.
+	PrivXPropertyMatchPathFirst PrivXPropertyMatch = "pathFirst"
+)
+
+// PrivXKeyNormalization selects how keys are rewritten to valid Kubernetes Secret keys.
+type PrivXKeyNormalization string
+
+const (
+	// PrivXKeyNormalizationOff returns the keys unchanged.
+	PrivXKeyNormalizationOff PrivXKeyNormalization = "off"
+
+	// PrivXKeyNormalizationRelaxed replaces the characters outside [-._a-zA-Z0-9] with "_".
+	PrivXKeyNormalizationRelaxed PrivXKeyNormalization = "relaxed"
+
+	// PrivXKeyNormalizationStrict normalizes like relaxed and lowercases the keys.
+	PrivXKeyNormalizationStrict PrivXKeyNormalization = "strict"
+)
+
+// PrivXVaultScope selects a PrivX vault.
+type PrivXVaultScope string
+
+const (
+	// PrivXVaultScopeShared is the shared vault with role based access.
+	PrivXVaultScopeShared PrivXVaultScope = "shared"
+
+	// PrivXVaultScopePersonal is the personal vault of a PrivX user.
+	PrivXVaultScopePersonal PrivXVaultScope = "personal"
+)
+
 // PrivXAuth contains the information needed for authentication towards PrivX.
 //
 // Use only one of the authentication options.
@@ -48,11 +286,33 @@ type PrivXAuth struct {
 }
 
 // PrivXOAuth contains the information needed for authentication with OAuth2.
+//
+// Use either CredentialsSecretRef or all four explicit references.
 type PrivXOAuth struct {
-	ClientIDRef        esmeta.SecretKeySelector `json:"clientIDRef"`
-	ClientSecretRef    esmeta.SecretKeySelector `json:"clientSecretRef"`
-	ApiClientIDRef     esmeta.SecretKeySelector `json:"apiClientIDRef"`
-	ApiClientSecretRef esmeta.SecretKeySelector `json:"apiClientSecretRef"`
+	// CredentialsSecretRef references a single Secret holding all OAuth credentials
+	// under the keys clientID, clientSecret, apiClientID and apiClientSecret.
+	// +optional
+	CredentialsSecretRef *PrivXCredentialsSecretRef `json:"credentialsSecretRef,omitempty"`
+
+	// +optional
+	ClientIDRef esmeta.SecretKeySelector `json:"clientIDRef,omitempty"`
+	// +optional
+	ClientSecretRef esmeta.SecretKeySelector `json:"clientSecretRef,omitempty"`
+	// +optional
+	ApiClientIDRef esmeta.SecretKeySelector `json:"apiClientIDRef,omitempty"`
+	// +optional
+	ApiClientSecretRef esmeta.SecretKeySelector `json:"apiClientSecretRef,omitempty"`
+}
+
+// PrivXCredentialsSecretRef is a reference to a Secret holding all PrivX OAuth credentials.
+type PrivXCredentialsSecretRef struct {
+	// The name of the Secret resource being referred to.
+	Name string `json:"name"`
+
+	// The namespace of the Secret resource being referred to.
+	// Ignored if referent is not cluster-scoped, otherwise defaults to the namespace of the referent.
+	// +optional
+	Namespace *string `json:"namespace,omitempty"`
 }
 
 // PrivxJWTAuth contains the information needed for authentication with explicit public key.
@@ -0,0 +1,72 @@
+/*
+Store binary values pushed to PrivX as base64 strings.
+*/
+
+package privx
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrInvalidBinary = errors.New("invalid base64 value")
+
+// A binary value is stored as a base64 string under its key, next to a marker key
+// made of the key and encodingSuffix holding encodingBase64, e.g. "cert" and
+// "cert.encoding". Reads decode marked values and hide the markers.
+const (
+	encodingSuffix = ".encoding"
+	encodingBase64 = "base64"
+)
+
+// encodeBinary replaces the values that are not valid UTF-8 by their base64
+// encoding and marks them, see encodingSuffix.
+func encodeBinary(values map[string]interface{}) {
+	for key, v := range values {
+		if b, ok := v.([]byte); ok && !utf8.Valid(b) {
+			values[key] = base64.StdEncoding.EncodeToString(b)
+			values[key+encodingSuffix] = encodingBase64
+		}
+	}
+}
+
+// isBinaryMarker reports whether key marks a base64 encoded value in data.
+func isBinaryMarker(data map[string]interface{}, key string) bool {
+	base, ok := strings.CutSuffix(key, encodingSuffix)
+	if !ok || data[key] != encodingBase64 {
+		return false
+	}
+	_, isString := data[base].(string)
+	return isString
+}
+
+// decodeBinary returns the named secret data with the marked values decoded to
+// bytes and without the markers. Data without markers is returned as is.
+func decodeBinary(secretName string, data map[string]interface{}) (map[string]interface{}, error) {
+	var decoded map[string]interface{}
+	for key := range data {
+		if !isBinaryMarker(data, key) {
+			continue
+		}
+		if decoded == nil {
+			decoded = make(map[string]interface{}, len(data))
+			for k, v := range data {
+				decoded[k] = v
+			}
+		}
+		base := strings.TrimSuffix(key, encodingSuffix)
+		b, err := base64.StdEncoding.DecodeString(data[base].(string))
+		if err != nil {
+			return nil, fmt.Errorf("secret %q key %q: %w: %w", secretName, base, ErrInvalidBinary, err)
+		}
+		decoded[base] = b
+		delete(decoded, key)
+	}
+	if decoded == nil {
+		return data, nil
+	}
+	return decoded, nil
+}
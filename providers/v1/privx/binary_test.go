@@ -0,0 +1,146 @@
+package privx
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	testingfake "github.com/external-secrets/external-secrets/runtime/testing/fake"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	binary := make([]byte, 64)
+	if _, err := rand.Read(binary); err != nil {
+		t.Fatal(err)
+	}
+	// A lone continuation byte is never valid UTF-8
+	binary[0] = 0x80
+
+	v := newFakeVault()
+	c := newTestClient(v)
+	ctx := context.Background()
+	source := testSecret(map[string][]byte{"cert": binary, "password": []byte("s3cr3t")})
+	for _, key := range []string{"cert", "password"} {
+		if err := c.PushSecret(ctx, source, testingfake.PushSecretData{SecretKey: key, RemoteKey: "app-" + key}); err != nil {
+			t.Fatalf("PushSecret(%s) error = %v", key, err)
+		}
+	}
+
+	stored := *v.secrets["app-cert"].Data
+	if stored["cert"+encodingSuffix] != encodingBase64 {
+		t.Errorf("stored data = %v, want the value marked as base64", stored)
+	}
+	if _, marked := (*v.secrets["app-password"].Data)["password"+encodingSuffix]; marked {
+		t.Errorf("UTF-8 value marked as base64")
+	}
+
+	got, err := c.GetSecret(ctx, esv1.ExternalSecretDataRemoteRef{Key: "app-cert", Property: "cert"})
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if !bytes.Equal(got, binary) {
+		t.Errorf("GetSecret() = %x, want %x", got, binary)
+	}
+
+	m, err := c.GetSecretMap(ctx, esv1.ExternalSecretDataRemoteRef{Key: "app-cert"})
+	if err != nil {
+		t.Fatalf("GetSecretMap() error = %v", err)
+	}
+	if len(m) != 1 || !bytes.Equal(m["cert"], binary) {
+		t.Errorf("GetSecretMap() = %v, want only the decoded cert", m)
+	}
+
+	// Whole secrets are JSON, holding the base64 string without the marker
+	want := fmt.Sprintf(`{"cert":%q}`, base64.StdEncoding.EncodeToString(binary))
+	whole, err := c.GetSecret(ctx, esv1.ExternalSecretDataRemoteRef{Key: "app-cert"})
+	if err != nil {
+		t.Fatalf("GetSecret(whole) error = %v", err)
+	}
+	if string(whole) != want {
+		t.Errorf("GetSecret(whole) = %s, want %s", whole, want)
+	}
+	found, err := c.GetAllSecrets(ctx, esv1.ExternalSecretFind{
+		Name:               &esv1.FindName{RegExp: "^app-cert$"},
+		ConversionStrategy: esv1.ExternalSecretConversionDefault,
+	})
+	if err != nil {
+		t.Fatalf("GetAllSecrets() error = %v", err)
+	}
+	if string(found["app-cert"]) != want {
+		t.Errorf("GetAllSecrets() = %s, want %s", found["app-cert"], want)
+	}
+}
+
+func TestBinaryMarkerReplaced(t *testing.T) {
+	v := newFakeVault()
+	c := newTestClient(v)
+	c.referenceCounting = true
+	ctx := context.Background()
+	push := testingfake.PushSecretData{SecretKey: "cert", RemoteKey: "shared", Property: "cert"}
+
+	if err := c.PushSecret(ctx, testSecret(map[string][]byte{"cert": {0xff, 0xfe}}), push); err != nil {
+		t.Fatalf("PushSecret(binary) error = %v", err)
+	}
+	if err := c.PushSecret(ctx, testSecret(map[string][]byte{"cert": []byte("text")}), push); err != nil {
+		t.Fatalf("PushSecret(text) error = %v", err)
+	}
+
+	got, err := c.GetSecret(ctx, esv1.ExternalSecretDataRemoteRef{Key: "shared", Property: "cert"})
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if string(got) != "text" {
+		t.Errorf("GetSecret() = %q, want the text value", got)
+	}
+}
+
+func TestDecodeBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]interface{}
+		want    map[string]interface{}
+		wantErr error
+	}{
+		{
+			name: "marked",
+			data: map[string]interface{}{"cert": "/w==", "cert.encoding": "base64"},
+			want: map[string]interface{}{"cert": []byte{0xff}},
+		},
+		{
+			name: "other encoding",
+			data: map[string]interface{}{"cert": "/w==", "cert.encoding": "hex"},
+			want: map[string]interface{}{"cert": "/w==", "cert.encoding": "hex"},
+		},
+		{
+			name: "marker without value",
+			data: map[string]interface{}{"cert.encoding": "base64"},
+			want: map[string]interface{}{"cert.encoding": "base64"},
+		},
+		{
+			name:    "invalid base64",
+			data:    map[string]interface{}{"cert": "not base64!", "cert.encoding": "base64"},
+			wantErr: ErrInvalidBinary,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := decodeBinary("app", tc.data)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("decodeBinary() error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("decodeBinary() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
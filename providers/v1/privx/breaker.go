@@ -0,0 +1,223 @@
+/*
+Stop calling an unavailable PrivX host for a while.
+*/
+
+package privx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/filters"
+	"github.com/SSHcom/privx-sdk-go/v2/api/response"
+	"github.com/SSHcom/privx-sdk-go/v2/api/vault"
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+// Defaults for the circuit breaker, used when the store leaves them unset.
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+var ErrCircuitOpen = errors.New("PrivX circuit open")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker fails calls to a PrivX host immediately while the host is
+// considered unavailable.
+//
+// The circuit opens after threshold consecutive host failures, see isHostFailure.
+// Once cooldown has passed, it half-opens and lets a single call probe the host:
+// the circuit closes when the probe reaches PrivX and opens again otherwise.
+type circuitBreaker struct {
+	host string
+	now  func() time.Time
+
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     breakerState
+	failures  int
+	openedAt  time.Time
+}
+
+// breakers are the circuit breakers by PrivX host, shared by the clients of all
+// stores so that the state outlives a single reconcile.
+var breakers = struct {
+	sync.Mutex
+	byHost map[string]*circuitBreaker
+}{byHost: map[string]*circuitBreaker{}}
+
+// breakerFor returns the circuit breaker of the store host, or nil when the store
+// does not enable one. The settings of the most recent store apply to the host.
+func breakerFor(privxSpec *esv1.PrivxProvider) *circuitBreaker {
+	config := privxSpec.CircuitBreaker
+	if config == nil {
+		return nil
+	}
+
+	threshold := defaultBreakerThreshold
+	if config.FailureThreshold != nil {
+		threshold = *config.FailureThreshold
+	}
+	cooldown := defaultBreakerCooldown
+	if config.Cooldown != nil {
+		cooldown = config.Cooldown.Duration
+	}
+
+	breakers.Lock()
+	defer breakers.Unlock()
+	b := breakers.byHost[privxSpec.Host]
+	if b == nil {
+		b = newCircuitBreaker(privxSpec.Host, threshold, cooldown)
+		breakers.byHost[privxSpec.Host] = b
+		return b
+	}
+	b.mu.Lock()
+	b.threshold, b.cooldown = threshold, cooldown
+	b.mu.Unlock()
+	return b
+}
+
+func newCircuitBreaker(host string, threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{host: host, now: time.Now, threshold: threshold, cooldown: cooldown}
+}
+
+// allow returns ErrCircuitOpen when a call may not be made now.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		retryAt := b.openedAt.Add(b.cooldown)
+		if b.now().Before(retryAt) {
+			return fmt.Errorf("%w: %s unavailable, next attempt after %s", ErrCircuitOpen, b.host, retryAt.Format(time.RFC3339))
+		}
+		b.state = breakerHalfOpen
+		return nil
+	case breakerHalfOpen:
+		// Another call is probing the host
+		return fmt.Errorf("%w: %s unavailable, probing", ErrCircuitOpen, b.host)
+	default:
+		return nil
+	}
+}
+
+// record updates the circuit with the result of an allowed call.
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if errors.Is(err, context.Canceled) {
+		// The call tells nothing about the host, let the next call probe it
+		if b.state == breakerHalfOpen {
+			b.state = breakerOpen
+		}
+		return
+	}
+	if !isHostFailure(err) {
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// do calls op unless the circuit is open.
+func (b *circuitBreaker) do(op func() error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := op()
+	b.record(err)
+	return err
+}
+
+// isHostFailure reports whether err shows the PrivX host unavailable: it could
+// not be reached in time, or it responded 5xx. Other errors, e.g. 404, show the
+// host working.
+func isHostFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError
+	}
+	return errors.Is(err, ErrConnect)
+}
+
+// guarded calls op through the circuit breaker b.
+func guarded[T any](b *circuitBreaker, op func() (T, error)) (T, error) {
+	var result T
+	err := b.do(func() error {
+		var err error
+		result, err = op()
+		return err
+	})
+	return result, err
+}
+
+// breakerVault is a vault guarded by a circuit breaker.
+type breakerVault struct {
+	vault   vaultClient
+	breaker *circuitBreaker
+}
+
+// withBreaker guards v by b, or returns v itself when b is nil.
+func withBreaker(v vaultClient, b *circuitBreaker) vaultClient {
+	if b == nil {
+		return v
+	}
+	return breakerVault{vault: v, breaker: b}
+}
+
+func (v breakerVault) GetSecret(secretName string) (*vault.Secret, error) {
+	return guarded(v.breaker, func() (*vault.Secret, error) { return v.vault.GetSecret(secretName) })
+}
+
+func (v breakerVault) GetSecretsMetadata(secretName string) (*vault.Secret, error) {
+	return guarded(v.breaker, func() (*vault.Secret, error) { return v.vault.GetSecretsMetadata(secretName) })
+}
+
+func (v breakerVault) GetSecrets(opts ...filters.Option) (*response.ResultSet[vault.Secret], error) {
+	return guarded(v.breaker, func() (*response.ResultSet[vault.Secret], error) { return v.vault.GetSecrets(opts...) })
+}
+
+func (v breakerVault) CreateSecret(secret *vault.SecretRequest) (vault.SecretCreate, error) {
+	return guarded(v.breaker, func() (vault.SecretCreate, error) { return v.vault.CreateSecret(secret) })
+}
+
+func (v breakerVault) GetSecretData(secretName string) (json.RawMessage, error) {
+	getter, ok := v.vault.(secretDataGetter)
+	if !ok {
+		return nil, fmt.Errorf("%w: secret %q", ErrNotObject, secretName)
+	}
+	return guarded(v.breaker, func() (json.RawMessage, error) { return getter.GetSecretData(secretName) })
+}
+
+func (v breakerVault) UpdateSecret(secretName string, secret *vault.SecretRequest) error {
+	return v.breaker.do(func() error { return v.vault.UpdateSecret(secretName, secret) })
+}
+
+func (v breakerVault) DeleteSecret(secretName string) error {
+	return v.breaker.do(func() error { return v.vault.DeleteSecret(secretName) })
+}
@@ -0,0 +1,139 @@
+package privx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"k8s.io/utils/ptr"
+)
+
+// testBreaker returns a circuit breaker with a clock advanced by the returned func.
+func testBreaker(threshold int, cooldown time.Duration) (*circuitBreaker, func(time.Duration)) {
+	b := newCircuitBreaker("https://privx.example.com", threshold, cooldown)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+	return b, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	unavailable := &StatusError{StatusCode: http.StatusServiceUnavailable, Err: errors.New("unavailable")}
+
+	b, advance := testBreaker(3, time.Minute)
+	v := newFakeVault(fakeSecret("app", map[string]interface{}{"password": "s3cr3t"}))
+	guarded := withBreaker(v, b)
+
+	// Closed: failures below the threshold reach PrivX, other errors reset the count
+	v.getErr = unavailable
+	for range 2 {
+		_, _ = guarded.GetSecret("app")
+	}
+	v.getErr = errFakeNotFound
+	_, _ = guarded.GetSecret("app")
+	v.getErr = unavailable
+	for range 2 {
+		_, _ = guarded.GetSecret("app")
+	}
+	if b.state != breakerClosed || v.getCalls != 5 {
+		t.Fatalf("state = %v after %d calls, want closed after 5", b.state, v.getCalls)
+	}
+
+	// Open: the threshold is reached and calls fail without reaching PrivX
+	_, _ = guarded.GetSecret("app")
+	if b.state != breakerOpen {
+		t.Fatalf("state = %v, want open", b.state)
+	}
+	_, err := guarded.GetSecret("app")
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("GetSecret() error = %v, want %v", err, ErrCircuitOpen)
+	}
+	if v.getCalls != 6 {
+		t.Errorf("getCalls = %d, want 6", v.getCalls)
+	}
+
+	// Half-open: after the cooldown a failing probe opens the circuit again
+	advance(time.Minute)
+	_, err = guarded.GetSecret("app")
+	if !errors.Is(err, unavailable) || b.state != breakerOpen {
+		t.Fatalf("probe error = %v, state = %v, want unavailable and open", err, b.state)
+	}
+	if _, err := guarded.GetSecret("app"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("GetSecret() error = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	// Closed: a successful probe closes the circuit
+	advance(time.Minute)
+	v.getErr = nil
+	if _, err := guarded.GetSecret("app"); err != nil {
+		t.Fatalf("probe error = %v", err)
+	}
+	if b.state != breakerClosed || b.failures != 0 {
+		t.Errorf("state = %v with %d failures, want closed with 0", b.state, b.failures)
+	}
+}
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	b, advance := testBreaker(1, time.Minute)
+	b.record(fmt.Errorf("%w: connection refused", ErrConnect))
+	advance(time.Minute)
+
+	if err := b.allow(); err != nil {
+		t.Fatalf("first allow() error = %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow() during probe error = %v, want %v", err, ErrCircuitOpen)
+	}
+	b.record(context.Canceled)
+	if err := b.allow(); err != nil {
+		t.Fatalf("allow() after canceled probe error = %v", err)
+	}
+	b.record(nil)
+	if err := b.allow(); err != nil || b.state != breakerClosed {
+		t.Errorf("allow() after probe error = %v, state = %v, want closed", err, b.state)
+	}
+}
+
+func TestIsHostFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "success"},
+		{name: "unreachable", err: fmt.Errorf("%w: connection refused", ErrConnect), want: true},
+		{name: "timed out", err: fmt.Errorf("%w: %w", ErrConnect, context.DeadlineExceeded), want: true},
+		{name: "canceled", err: fmt.Errorf("%w: %w", ErrConnect, context.Canceled)},
+		{name: "server error", err: &StatusError{StatusCode: http.StatusBadGateway, Err: errors.New("bad gateway")}, want: true},
+		{name: "too many requests", err: &StatusError{StatusCode: http.StatusTooManyRequests, Err: errors.New("slow down")}},
+		{name: "not found", err: &StatusError{StatusCode: http.StatusNotFound, Err: errFakeNotFound}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isHostFailure(tc.err); got != tc.want {
+				t.Errorf("isHostFailure(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBreakerFor(t *testing.T) {
+	if b := breakerFor(&esv1.PrivxProvider{Host: "https://disabled.example.com"}); b != nil {
+		t.Fatalf("breakerFor() = %v without circuitBreaker, want nil", b)
+	}
+
+	spec := &esv1.PrivxProvider{Host: "https://breaker.example.com", CircuitBreaker: &esv1.PrivXCircuitBreaker{}}
+	b := breakerFor(spec)
+	if b.threshold != defaultBreakerThreshold || b.cooldown != defaultBreakerCooldown {
+		t.Errorf("breakerFor() threshold = %d, cooldown = %s, want defaults", b.threshold, b.cooldown)
+	}
+
+	spec.CircuitBreaker.FailureThreshold = ptr.To(2)
+	if again := breakerFor(spec); again != b || again.threshold != 2 {
+		t.Errorf("breakerFor() = %p with threshold %d, want %p with threshold 2", again, again.threshold, b)
+	}
+}
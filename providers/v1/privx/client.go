@@ -5,20 +5,27 @@ Implement the ESO SecretsClient
 package privx
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/SSHcom/privx-sdk-go/v2/api/filters"
+	"github.com/SSHcom/privx-sdk-go/v2/api/response"
 	"github.com/SSHcom/privx-sdk-go/v2/api/rolestore"
 	"github.com/SSHcom/privx-sdk-go/v2/api/vault"
-	privxapi "github.com/SSHcom/privx-sdk-go/v2/restapi"
 	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/runtime/esutils/metadata"
+	"golang.org/x/sync/singleflight"
 	corev1 "k8s.io/api/core/v1"
 	kclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -29,27 +36,178 @@ var (
 	ErrUnsupportedDecodingStrategy = errors.New("unsupported decoding strategy")
 	ErrSecretDataMissing           = errors.New("secret data missing")
 	ErrPropertyNotFound            = errors.New("property not found in secret")
+	ErrOwnerRejected               = errors.New("PrivX rejected the secret owner")
+	ErrValueNotSerializable        = errors.New("value cannot be serialized")
+	ErrRolesForbidden              = errors.New("PrivX denied writing the secret with the requested roles")
+	ErrWriteNotVerified            = errors.New("pushed value not found when read back")
+	ErrPingFailed                  = errors.New("PrivX ping failed")
 )
 
 // Check during compile that we implement the interface
 var _ esv1.SecretsClient = (*SecretsClient)(nil)
 
+// vaultClient is the subset of the PrivX Vault API used by SecretsClient.
+type vaultClient interface {
+	GetSecret(secretName string) (*vault.Secret, error)
+	GetSecretsMetadata(secretName string) (*vault.Secret, error)
+	GetSecrets(opts ...filters.Option) (*response.ResultSet[vault.Secret], error)
+	CreateSecret(secret *vault.SecretRequest) (vault.SecretCreate, error)
+	UpdateSecret(secretName string, secret *vault.SecretRequest) error
+	DeleteSecret(secretName string) error
+}
+
+// Check during compile that the SDK Vault satisfies vaultClient
+var _ vaultClient = (*vault.Vault)(nil)
+
+// PushSecretMetadataSpec contains the PrivX specific PushSecret metadata.
+//
+//	apiVersion: kubernetes.external-secrets.io/v1alpha1
+//	kind: PushSecretMetadata
+//	spec:
+//	  owner: <PrivX user ID>
+//	  fanOut: true
+type PushSecretMetadataSpec struct {
+	// Owner is the PrivX user ID recorded as the owner of a created secret.
+	// When empty, PrivX records the authenticated client as the owner.
+	Owner string `json:"owner,omitempty"`
+
+	// FanOut pushes every key of the secret to a PrivX secret of its own,
+	// named by the remote key followed by the key. The secretKey must be empty.
+	FanOut bool `json:"fanOut,omitempty"`
+}
+
 // SecretsClient provides access to PrivX secrets.
 type SecretsClient struct {
-	conn      privxapi.Connector
-	vault     *vault.Vault // PrivX Vault instance
+	conn      *connector
+	vault     vaultClient // PrivX Vault instance
 	store     esv1.GenericStore
 	kube      kclient.Client
 	namespace string
 
+	// sharedConn is set when conn is shared with other clients, which keep
+	// using its connections after Close.
+	sharedConn bool
+
 	// PrivX needs roles when creating a new secret.
 	defaultReadRoles  []string
 	defaultWriteRoles []string
+
+	// nameTemplate derives pushed secret names, when set.
+	nameTemplate *template.Template
+
+	// findMetadataOnly makes GetAllSecrets return metadata instead of data.
+	findMetadataOnly bool
+
+	// referenceCounting shares pushed secrets, see addRef and releaseRef.
+	referenceCounting bool
+
+	// newVault binds the Vault API to the context of an operation, see withTimeout.
+	// When nil, vault is used as is.
+	newVault func(ctx context.Context) vaultClient
+
+	// requestTimeout limits single secret operations and findTimeout GetAllSecrets.
+	requestTimeout time.Duration
+	findTimeout    time.Duration
+
+	// keys restricts the secret names the client may access.
+	keys *keyPolicy
+
+	// retry retries transiently failing requests.
+	retry retryPolicy
+
+	// verifyAfterWrite reads pushed secrets back, see verifyWrite.
+	verifyAfterWrite bool
+
+	// properties configure how ref.Property is resolved.
+	properties propertyOptions
+
+	// managedOnly refuses to push into secrets not created by PushSecret, see pushManaged.
+	managedOnly bool
+
+	// transform shapes the string values returned by GetSecret.
+	transform valueTransform
+
+	// flattenSecretMap makes GetSecretMap return the leaves of nested objects, see flattenObject.
+	flattenSecretMap bool
+
+	// maxSecretSize limits the size of the secrets read and pushed, see checkSize.
+	maxSecretSize int
+
+	// maxFindResults and maxFindBytes bound the result of GetAllSecrets,
+	// see checkFindResults and checkFindSize.
+	maxFindResults int
+	maxFindBytes   int
+
+	// keyNormalization rewrites the keys returned by GetSecretMap and GetAllSecrets, see normalizeKeys.
+	keyNormalization esv1.PrivXKeyNormalization
+
+	// rejectObjectProperty makes GetSecret fail on a property selecting an object
+	// instead of returning the object as JSON.
+	rejectObjectProperty bool
+
+	// requireManagedByLabel makes PushSecret mark the secrets it writes and
+	// DeleteSecret refuse secrets without the marker, see checkDeletable.
+	requireManagedByLabel bool
+
+	// requireProperty makes GetSecret and GetSecretMap fail without a property, see checkProperty.
+	requireProperty bool
+
+	// reads coalesces concurrent reads, see readSecret. Nil disables coalescing.
+	reads *singleflight.Group
+	// readScope tells the vaults of stores sharing reads apart.
+	readScope string
+}
+
+// withTimeout returns a copy of the client whose vault requests are bound to ctx,
+// limited by timeout when set. cancel must be called when the operation is done.
+func (c *SecretsClient) withTimeout(ctx context.Context, timeout time.Duration) (*SecretsClient, context.CancelFunc) {
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	bound := *c
+	if c.newVault != nil {
+		bound.vault = c.newVault(ctx)
+	}
+	return &bound, cancel
 }
 
 // GetSecret returns a single secret from the provider.
-func (c *SecretsClient) GetSecret(ctx context.Context, ref esv1.ExternalSecretDataRemoteRef) ([]byte, error) {
-	secret, err := c.vault.GetSecret(ref.Key)
+//
+// ref.Property is resolved as described in resolveProperty. A comma-separated list
+// of properties returns a JSON object of the selected values, see resolveProperties.
+// A property prefixed with "jmespath:" is a JMESPath expression, see searchJMESPath,
+// and "_roles.read" and "_roles.write" select the roles of the secret, see getSecretRoles.
+// A selected string value is shaped by the store valueTransform, see valueTransform.
+// With metadataPolicy Fetch the secret metadata is returned instead of its data.
+func (c *SecretsClient) GetSecret(ctx context.Context, ref esv1.ExternalSecretDataRemoteRef) (_ []byte, err error) {
+	ctx, span := startSpan(ctx, "GetSecret", ref.Key)
+	defer func() { endSpan(span, err) }()
+
+	c, cancel := c.withTimeout(ctx, c.requestTimeout)
+	defer cancel()
+
+	log.FromContext(ctx).V(logDebug).Info("getting PrivX secret",
+		"key", ref.Key, "property", ref.Property, "metadataPolicy", ref.MetadataPolicy)
+
+	if err := c.keys.check(ref.Key); err != nil {
+		return nil, err
+	}
+
+	if ref.MetadataPolicy == esv1.ExternalSecretMetadataPolicyFetch {
+		return c.getSecretMetadata(ref)
+	}
+	if err := c.checkProperty(ref); err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(ref.Property, rolesPropertyPrefix) {
+		return c.getSecretRoles(ref)
+	}
+
+	secret, err := c.readSecret(ctx, ref.Key)
+	if isNotObject(err) {
+		return c.getNonObjectSecret(ref)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -57,24 +215,116 @@ func (c *SecretsClient) GetSecret(ctx context.Context, ref esv1.ExternalSecretDa
 		return nil, fmt.Errorf("%w: %s", ErrSecretDataMissing, ref.Key)
 	}
 
+	data := visibleData(*secret.Data)
+	if err := c.checkReadSize(ref.Key, data); err != nil {
+		return nil, err
+	}
+	data, err = decodeBinary(ref.Key, data)
+	if err != nil {
+		return nil, err
+	}
+
 	// If no property requested, return whole JSON object
 	if ref.Property == "" {
-		return json.Marshal(*secret.Data)
+		b, err := marshalData(ref.Key, data)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.checkSize(ref.Key, len(b)); err != nil {
+			return nil, err
+		}
+		return b, nil
 	}
 
-	v, ok := (*secret.Data)[ref.Property]
-	if !ok || v == nil {
-		return nil, fmt.Errorf("%w: %s/%s", ErrPropertyNotFound, ref.Key, ref.Property)
+	var v any
+	if expression, ok := strings.CutPrefix(ref.Property, jmesPathPrefix); ok {
+		v, err = searchJMESPath(data, expression)
+	} else {
+		v, err = resolveProperties(data, ref.Property, c.properties)
+		if _, isObject := v.(map[string]interface{}); isObject && c.rejectObjectProperty &&
+			!isPropertyList(data, ref.Property, c.properties) {
+			return nil, fmt.Errorf("secret %q property %q: %w; use dataFrom.extract with the property to sync its fields as keys",
+				ref.Key, ref.Property, ErrObjectProperty)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ref.Key, err)
 	}
 
 	// Convert the selected value to []byte
 	b, err := anyToBytes(v)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("secret %q key %q: %w", ref.Key, ref.Property, err)
+	}
+	if _, ok := v.(string); ok {
+		// ESO applies the decoding strategy to the transformed value
+		b = c.transform.apply(b)
 	}
 	return b, nil
 }
 
+// checkProperty fails with ErrPropertyRequired when the store requires a property
+// and ref has none.
+func (c *SecretsClient) checkProperty(ref esv1.ExternalSecretDataRemoteRef) error {
+	if c.requireProperty && ref.Property == "" {
+		return fmt.Errorf("secret %q: %w, the store does not return whole secrets", ref.Key, ErrPropertyRequired)
+	}
+	return nil
+}
+
+// readSecret reads the named secret for GetSecret and GetSecretMap.
+//
+// Concurrent reads of the same secret in the same vault, e.g. by the ExternalSecrets
+// of a rolling deployment, share a single PrivX request and its result. The request
+// is bound by the request timeout of the store only, not by the context of a caller,
+// so that a caller giving up does not fail the others; each caller waits for it under
+// its own context. PrivX secrets have no versions, so the reads are keyed by name.
+// Reads before writes, e.g. by PushSecret, are never shared, as they must see the
+// secret as it is.
+func (c *SecretsClient) readSecret(ctx context.Context, name string) (*vault.Secret, error) {
+	if c.reads == nil {
+		return c.vault.GetSecret(name)
+	}
+	results := c.reads.DoChan(c.readScope+"/"+name, func() (interface{}, error) {
+		c, cancel := c.withTimeout(context.WithoutCancel(ctx), c.requestTimeout)
+		defer cancel()
+		return c.vault.GetSecret(name)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("secret %q: %w", name, ctx.Err())
+	case result := <-results:
+		if result.Shared {
+			log.FromContext(ctx).V(logTrace).Info("shared PrivX read with concurrent callers", "name", name)
+		}
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return result.Val.(*vault.Secret), nil
+	}
+}
+
+// getNonObjectSecret returns the data of a secret that is not a JSON object,
+// e.g. an array, as is. Such data has no properties to select.
+func (c *SecretsClient) getNonObjectSecret(ref esv1.ExternalSecretDataRemoteRef) ([]byte, error) {
+	if ref.Property != "" {
+		return nil, fmt.Errorf("%w: secret %q has no property %q", ErrNotObject, ref.Key, ref.Property)
+	}
+	getter, ok := c.vault.(secretDataGetter)
+	if !ok {
+		return nil, fmt.Errorf("%w: secret %q", ErrNotObject, ref.Key)
+	}
+	raw, err := getter.GetSecretData(ref.Key)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkSize(ref.Key, len(raw)); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
 // packRoles forms RoleHandles from a list of role ID
 //
 // The PrivX API will ignore the name field.
@@ -90,59 +340,220 @@ func packRoles(roleIDs []string) []rolestore.RoleHandle {
 // PushSecret will write a single secret into PrivX.
 //
 // Access for the new secret in PrivX is defined by variables default*Roles set for the store.
+// With fanOut metadata, every key of the secret is written to a PrivX secret of its own,
+// see pushFanOut.
 func (c *SecretsClient) PushSecret(ctx context.Context, secret *corev1.Secret, data esv1.PushSecretData) error {
+	meta, err := metadata.ParseMetadataParameters[PushSecretMetadataSpec](data.GetMetadata())
+	if err != nil {
+		return fmt.Errorf("failed to parse push secret metadata: %w", err)
+	}
+	var owner string
+	if meta != nil {
+		owner = meta.Spec.Owner
+		if meta.Spec.FanOut {
+			return c.pushFanOut(ctx, secret, data, owner)
+		}
+	}
+
 	remoteKey := data.GetRemoteKey()
 	name := remoteKey
 	if name == "" {
 		name = secret.Name
 	}
+	name, err = c.secretName(map[string]string{
+		nameVarNamespace:  c.namespace,
+		nameVarSecretName: secret.Name,
+		nameVarSecretKey:  data.GetSecretKey(),
+		nameVarRemoteKey:  remoteKey,
+		nameVarProperty:   data.GetProperty(),
+	}, name)
+	if err != nil {
+		return err
+	}
 	if name == "" {
 		return ErrNoName
 	}
 
 	secretKey := data.GetSecretKey()
-	secretValue := secret.Data[secretKey]
-	m := &map[string]interface{}{secretKey: secretValue}
+	dataKey := secretKey
+	if c.referenceCounting && data.GetProperty() != "" {
+		// Pushes sharing a secret keep their values under their own property
+		dataKey = data.GetProperty()
+	}
+	values := map[string]interface{}{dataKey: secret.Data[secretKey]}
+	return c.pushData(ctx, name, owner, data.GetProperty(), dataKey, values)
+}
+
+// pushData writes values into the named secret, key being the data key pushed.
+// ref is the reference of the push when the store counts references.
+func (c *SecretsClient) pushData(
+	ctx context.Context,
+	name, owner, ref, key string,
+	values map[string]interface{},
+) (err error) {
+	if err := c.keys.check(name); err != nil {
+		return err
+	}
+	if err := c.checkPushSize(name, values); err != nil {
+		return err
+	}
+	encodeBinary(values)
+	if c.requireManagedByLabel {
+		// Mark the secret so that it can be deleted again, see checkDeletable
+		values[managedKey] = true
+	}
+
+	ctx, span := startSpan(ctx, "PushSecret", name)
+	defer func() { endSpan(span, err) }()
+
+	c, cancel := c.withTimeout(ctx, c.requestTimeout)
+	defer cancel()
+
+	logger := log.FromContext(ctx).WithValues("name", name, "key", key)
+	logger.V(logDebug).Info("pushing PrivX secret", "property", ref,
+		"referenceCounting", c.referenceCounting, "managedOnly", c.managedOnly)
 
 	request := vault.SecretRequest{
 		Name:       name,
 		ReadRoles:  packRoles(c.defaultReadRoles),
 		WriteRoles: packRoles(c.defaultWriteRoles),
-		Data:       m,
+		Data:       &values,
+		OwnerID:    owner,
+	}
+	switch {
+	case c.referenceCounting:
+		err = c.addRef(&request, ref)
+	case c.managedOnly:
+		err = c.pushManaged(&request)
+	default:
+		err = c.modifyOrCreate(name, func(*vault.Secret) (*vault.SecretRequest, error) {
+			return &request, nil
+		}, func() error {
+			_, err := c.vault.CreateSecret(&request)
+			return err
+		})
 	}
-	_, err := c.vault.CreateSecret(&request)
 
+	if err != nil && owner != "" && isOwnerRejected(err) {
+		return fmt.Errorf("%w: owner %q for secret %q: %w", ErrOwnerRejected, owner, name, err)
+	}
+	if isForbidden(err) {
+		// An authorization failure, not a bad payload
+		return fmt.Errorf("%w: secret %q, readRoles %v, writeRoles %v: %w",
+			ErrRolesForbidden, name, c.defaultReadRoles, c.defaultWriteRoles, err)
+	}
 	if err != nil {
-		logger := log.FromContext(ctx)
-		logger.Error(
-			err,
-			"privx error",
+		// The error is returned to the caller, which reports it
+		logger.V(logDebug).Info("pushing PrivX secret failed",
 			"errorType", fmt.Sprintf("%T", err),
-			"remoteKey", name,
 			"readRoles", c.defaultReadRoles,
 			"writeRoles", c.defaultWriteRoles,
 		)
+		return err
 	}
-	return err
+
+	if c.verifyAfterWrite {
+		return c.verifyWrite(name, key, values[key])
+	}
+	return nil
+}
+
+// verifyWrite reads the named secret back and checks that it stores value under key.
+//
+// PrivX returns no version on writes, so the read cannot be pinned to the write;
+// a concurrent writer of the same key makes the verification fail.
+func (c *SecretsClient) verifyWrite(name, key string, value interface{}) error {
+	secret, err := c.vault.GetSecret(name)
+	if err != nil {
+		return fmt.Errorf("%w: secret %q: %w", ErrWriteNotVerified, name, err)
+	}
+
+	var stored interface{}
+	ok := false
+	if secret.Data != nil {
+		stored, ok = (*secret.Data)[key]
+	}
+	// Compare the JSON encodings, as a value is sent as bytes and read back as a string
+	want, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	got, err := json.Marshal(stored)
+	if !ok || err != nil || !bytes.Equal(want, got) {
+		return fmt.Errorf("%w: secret %q key %q", ErrWriteNotVerified, name, key)
+	}
+	return nil
 }
 
 // DeleteSecret will delete the secret from PrivX.
+//
+// With reference counting, only the reference of ref.Property is released and the
+// secret is deleted once no pushes refer to it.
+// The secrets of a fanned out push are found by the remote key, see deleteFanOut.
 func (c *SecretsClient) DeleteSecret(ctx context.Context, ref esv1.PushSecretRemoteRef) error {
-	err := c.vault.DeleteSecret(ref.GetRemoteKey())
+	name, err := c.remoteRefName(ref)
+	if err != nil {
+		return err
+	}
+
+	if err := c.keys.check(name); err != nil {
+		return err
+	}
+
+	err = c.deleteData(ctx, name, ref.GetProperty())
 	if err == nil {
 		return nil
 	}
 	if isNotFound(err) {
-		return nil
+		// A fanned out push has no secret of its own, see pushFanOut
+		log.FromContext(ctx).V(logDebug).Info("PrivX secret not found, deleting fanned out secrets", "remoteKey", ref.GetRemoteKey())
+		return c.deleteFanOut(ctx, ref)
 	}
 	return err
 }
 
+// deleteData deletes the named secret, or with reference counting releases ref from it.
+func (c *SecretsClient) deleteData(ctx context.Context, name, ref string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteSecret", name)
+	defer func() { endSpan(span, err) }()
+
+	c, cancel := c.withTimeout(ctx, c.requestTimeout)
+	defer cancel()
+
+	log.FromContext(ctx).V(logDebug).Info("deleting PrivX secret", "name", name, "property", ref,
+		"referenceCounting", c.referenceCounting)
+	if c.requireManagedByLabel {
+		if err := c.checkDeletable(name); err != nil {
+			return err
+		}
+	}
+	if c.referenceCounting {
+		return c.releaseRef(name, ref)
+	}
+	return c.vault.DeleteSecret(name)
+}
+
 // SecretExists checks if a secret is already present in PrivX at the given location.
-func (c *SecretsClient) SecretExists(ctx context.Context, ref esv1.PushSecretRemoteRef) (bool, error) {
+//
+// Transient failures are retried, so false means PrivX confirmed the secret does not
+// exist and an error is returned only once the retries are exhausted.
+func (c *SecretsClient) SecretExists(ctx context.Context, ref esv1.PushSecretRemoteRef) (_ bool, err error) {
+	name, err := c.remoteRefName(ref)
+	if err != nil {
+		return false, err
+	}
 
-	remoteRef := esv1.ExternalSecretDataRemoteRef{Key: ref.GetRemoteKey()}
-	_, err := c.GetSecret(context.TODO(), remoteRef)
+	ctx, span := startSpan(ctx, "SecretExists", name)
+	defer func() { endSpan(span, err) }()
+
+	// Only the metadata is read: the existence of a secret does not depend on the
+	// read options of the store, such as requireProperty or maxSecretSize
+	err = c.retry.do(ctx, func() error {
+		c, cancel := c.withTimeout(ctx, c.requestTimeout)
+		defer cancel()
+		_, err := c.vault.GetSecretsMetadata(name)
+		return err
+	})
 	if err == nil {
 		return true, nil
 	}
@@ -155,12 +566,27 @@ func (c *SecretsClient) SecretExists(ctx context.Context, ref esv1.PushSecretRem
 	return false, err
 }
 
+// remoteRefName returns the PrivX secret name for a PushSecret remote reference.
+//
+// The source secret is not known here, so a name template can only use
+// the namespace, the remote key and the property.
+func (c *SecretsClient) remoteRefName(ref esv1.PushSecretRemoteRef) (string, error) {
+	return c.secretName(map[string]string{
+		nameVarNamespace: c.namespace,
+		nameVarRemoteKey: ref.GetRemoteKey(),
+		nameVarProperty:  ref.GetProperty(),
+	}, ref.GetRemoteKey())
+}
+
 // Validate checks if the client is configured correctly
 // and is able to retrieve secrets from the provider.
 // If the validation result is unknown it will be ignored.
 func (c *SecretsClient) Validate() (esv1.ValidationResult, error) {
 
-	_, err := c.GetSecret(context.TODO(), esv1.ExternalSecretDataRemoteRef{Key: "2F0vZqCe0Z3XU5"})
+	// The probe goes to the vault directly, as the store policy may not permit its name
+	c, cancel := c.withTimeout(context.TODO(), c.requestTimeout)
+	defer cancel()
+	_, err := c.vault.GetSecret("2F0vZqCe0Z3XU5")
 
 	if isNotFound(err) {
 		// We requested a non-existing secret and this is the proper response from PrivX -- all ok.
@@ -170,17 +596,67 @@ func (c *SecretsClient) Validate() (esv1.ValidationResult, error) {
 	return esv1.ValidationResultError, err
 }
 
+// Ping checks that PrivX is reachable and accepts the credentials of the store,
+// e.g. for a readiness check. It lists a single secret of the vault, so it does
+// not depend on any secret existing, but needs permission to list the vault.
+//
+// The call is bound by ctx and the request timeout of the store.
+// Failures wrap ErrPingFailed.
+func (c *SecretsClient) Ping(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "Ping", "")
+	defer func() { endSpan(span, err) }()
+
+	c, cancel := c.withTimeout(ctx, c.requestTimeout)
+	defer cancel()
+	if _, err := c.vault.GetSecrets(filters.Limit(1)); err != nil {
+		return fmt.Errorf("%w: %w", ErrPingFailed, err)
+	}
+	return nil
+}
+
 // GetSecretMap returns multiple key/value pairs from a PrivX secret.
 //
 // If ref.Property is empty, all top-level keys are returned.
+// ref.Property is resolved as described in resolveProperty.
 // If ref.Property refers to a nested JSON object, its fields are returned.
+// With flattenSecretMap, nested objects are flattened into dotted keys.
 // Otherwise, a single key/value pair is returned containing the selected property.
+// The keys are normalized with the keyNormalization of the store, see normalizeKeys.
 func (c *SecretsClient) GetSecretMap(
 	ctx context.Context,
 	ref esv1.ExternalSecretDataRemoteRef,
-) (map[string][]byte, error) {
+) (_ map[string][]byte, err error) {
+	ctx, span := startSpan(ctx, "GetSecretMap", ref.Key)
+	defer func() { endSpan(span, err) }()
+
+	logger := log.FromContext(ctx).WithValues("key", ref.Key, "property", ref.Property)
+	logger.V(logDebug).Info("getting PrivX secret map")
+
+	secrets, err := c.getSecretMap(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	logger.V(logTrace).Info("got PrivX secret map", "keys", len(secrets))
+	return normalizeKeys(c.keyNormalization, secrets)
+}
+
+// getSecretMap returns the key/value pairs of GetSecretMap before normalizing the keys.
+func (c *SecretsClient) getSecretMap(ctx context.Context, ref esv1.ExternalSecretDataRemoteRef) (map[string][]byte, error) {
+	if err := c.keys.check(ref.Key); err != nil {
+		return nil, err
+	}
+	if err := c.checkProperty(ref); err != nil {
+		return nil, err
+	}
 
-	secret, err := c.vault.GetSecret(ref.Key)
+	c, cancel := c.withTimeout(ctx, c.requestTimeout)
+	defer cancel()
+
+	secret, err := c.readSecret(ctx, ref.Key)
+	if isNotObject(err) {
+		// A map takes the keys of an object, which such data has not
+		return nil, fmt.Errorf("%w: secret %q has no keys", ErrNotObject, ref.Key)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -189,45 +665,35 @@ func (c *SecretsClient) GetSecretMap(
 		return nil, ErrSecretDataMissing
 	}
 
-	data := *secret.Data
+	data := visibleData(*secret.Data)
+	if err := c.checkReadSize(ref.Key, data); err != nil {
+		return nil, err
+	}
+	data, err = decodeBinary(ref.Key, data)
+	if err != nil {
+		return nil, err
+	}
 
 	// 1) No property specified: return all top-level keys
 	if ref.Property == "" {
-		out := make(map[string][]byte, len(data))
-
-		for k, v := range data {
-			b, err := anyToBytes(v)
-			if err != nil {
-				return nil, err
-			}
-			out[k] = b
-		}
-		return out, nil
+		return c.objectMap(ref.Key, "", data)
 	}
 
 	// 2) Property specified: extract it
-	v, ok := data[ref.Property]
-	if !ok || v == nil {
-		return nil, ErrPropertyNotFound
+	v, err := resolveProperty(data, ref.Property, c.properties)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ref.Key, err)
 	}
 
 	// If property is a nested object, return its fields
 	if nested, ok := v.(map[string]interface{}); ok {
-		out := make(map[string][]byte, len(nested))
-		for k, nv := range nested {
-			b, err := anyToBytes(nv)
-			if err != nil {
-				return nil, err
-			}
-			out[k] = b
-		}
-		return out, nil
+		return c.objectMap(ref.Key, ref.Property+".", nested)
 	}
 
 	// Otherwise return a single key/value pair
 	b, err := anyToBytes(v)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("secret %q key %q: %w", ref.Key, ref.Property, err)
 	}
 
 	return map[string][]byte{
@@ -235,12 +701,45 @@ func (c *SecretsClient) GetSecretMap(
 	}, nil
 }
 
+// objectMap returns the fields of an object in the named secret as a map,
+// flattened with flattenSecretMap, see flattenObject. prefix is the path of
+// the object for error messages.
+func (c *SecretsClient) objectMap(name, prefix string, obj map[string]interface{}) (map[string][]byte, error) {
+	if c.flattenSecretMap {
+		flat, err := flattenObject(obj)
+		if err != nil {
+			return nil, fmt.Errorf("secret %q: %w", name, err)
+		}
+		obj = flat
+	}
+
+	out := make(map[string][]byte, len(obj))
+	for k, v := range obj {
+		b, err := anyToBytes(v)
+		if err != nil {
+			return nil, fmt.Errorf("secret %q key %q: %w", name, prefix+k, err)
+		}
+		out[k] = b
+	}
+	return out, nil
+}
+
 // GetAllSecrets returns multiple secrets and their JSON values from PrivX.
 //
 // The returned map key is the secret name and the value is the full JSON document
 // for that secret (the whole secret.Data marshaled as JSON). This avoids key
 // collisions between secrets that may contain identical JSON keys internally.
-func (c *SecretsClient) GetAllSecrets(ctx context.Context, ref esv1.ExternalSecretFind) (map[string][]byte, error) {
+//
+// With findMetadataOnly the value is the secret metadata instead, taken from the
+// list response without fetching any secret data.
+func (c *SecretsClient) GetAllSecrets(ctx context.Context, ref esv1.ExternalSecretFind) (_ map[string][]byte, err error) {
+	ctx, span := startSpan(ctx, "GetAllSecrets", "")
+	defer func() { endSpan(span, err) }()
+
+	// The find timeout covers listing and fetching all matching secrets
+	c, cancel := c.withTimeout(ctx, c.findTimeout)
+	defer cancel()
+
 	results := make(map[string][]byte)
 
 	if ref.Path != nil {
@@ -264,63 +763,179 @@ func (c *SecretsClient) GetAllSecrets(ctx context.Context, ref esv1.ExternalSecr
 		return results, fmt.Errorf("invalid regex %q: %w", searchString, err)
 	}
 
-	// Loop through all secrets 100 at a time
-	const limit = 100
-	for offset := 0; ; offset += limit {
-		secrets, err := c.vault.GetSecrets(filters.Limit(limit), filters.Offset(offset))
-		if err != nil {
-			return results, err
-		}
+	logger := log.FromContext(ctx)
+	logger.V(logDebug).Info("finding PrivX secrets", "regexp", searchString, "metadataOnly", c.findMetadataOnly)
 
-		if secrets.Count == 0 {
-			break
+	// The results are bounded as they accumulate, so that a broad find fails
+	// before the whole vault is loaded into memory
+	size := 0
+	err = c.forEachSecret(func(secret vault.Secret) error {
+		if !nameRegexp.MatchString(secret.Name) || !c.keys.permits(secret.Name) {
+			return nil
 		}
+		if err := c.checkFindResults(len(results)); err != nil {
+			return err
+		}
+		logger.V(logTrace).Info("found PrivX secret", "name", secret.Name)
 
-		for _, secret := range secrets.Items {
-			if !nameRegexp.MatchString(secret.Name) {
-				continue
-			}
-
-			secretDetails, err := c.vault.GetSecret(secret.Name)
+		if c.findMetadataOnly {
+			b, err := json.Marshal(newSecretMetadata(&secret))
 			if err != nil {
-				return results, err
+				return err
 			}
-
-			if secretDetails.Data == nil {
-				return results, ErrSecretDataMissing
+			size += len(b)
+			if err := c.checkFindSize(size); err != nil {
+				return err
 			}
+			results[secret.Name] = b
+			return nil
+		}
 
-			// Marshal the full JSON object (top-level map) as the secret value
-			b, err := json.Marshal(*secretDetails.Data)
+		// Use the data of the list item when PrivX includes it, saving a
+		// request per secret, and fetch the secret otherwise. PrivX secrets have
+		// no ID: the name is the unique key of a secret within its vault, and the
+		// fetch goes to the vault that was listed, so it cannot return another secret.
+		data := secret.Data
+		if data == nil {
+			secretDetails, err := c.vault.GetSecret(secret.Name)
 			if err != nil {
-				return results, err
+				return err
 			}
+			data = secretDetails.Data
+		}
 
-			results[secret.Name] = b
+		if data == nil {
+			return ErrSecretDataMissing
 		}
 
-		if secrets.Count < limit {
-			break
+		// Marshal the full JSON object (top-level map) as the secret value. Decoded
+		// binary values are encoded to base64 again, without their markers.
+		decoded, err := decodeBinary(secret.Name, visibleData(*data))
+		if err != nil {
+			return err
+		}
+		b, err := marshalData(secret.Name, decoded)
+		if err != nil {
+			return err
+		}
+		if err := c.checkSize(secret.Name, len(b)); err != nil {
+			return err
 		}
+		size += len(b)
+		if err := c.checkFindSize(size); err != nil {
+			return err
+		}
+
+		results[secret.Name] = b
+		return nil
+	})
+	if err != nil {
+		return results, err
 	}
 
-	return results, nil
+	logger.V(logDebug).Info("found PrivX secrets", "count", len(results), "bytes", size)
+	return normalizeKeys(c.keyNormalization, results)
+}
+
+// listPageSize is the number of secrets requested per page when listing the vault.
+const listPageSize = 100
+
+// forEachSecret calls fn for every secret listed in the vault, one page at a time.
+//
+// The PrivX Vault API only supports offset paging, there is no cursor to resume from.
+// Paging stops at the first page shorter than listPageSize, as the count in the
+// response is the total number of secrets rather than the size of the page.
+func (c *SecretsClient) forEachSecret(fn func(vault.Secret) error) error {
+	for offset := 0; ; offset += listPageSize {
+		secrets, err := c.vault.GetSecrets(filters.Limit(listPageSize), filters.Offset(offset))
+		if err != nil {
+			return err
+		}
+
+		for _, secret := range secrets.Items {
+			if err := fn(secret); err != nil {
+				return err
+			}
+		}
+
+		if len(secrets.Items) < listPageSize {
+			return nil
+		}
+	}
 }
 
 // Close closes the client and releases all resources.
+//
+// Idle connections are closed, which also ends their transport goroutines,
+// unless the connection is shared with other clients; the connector cache then
+// closes them when dropping it. The client starts no other background goroutines.
 func (c *SecretsClient) Close(ctx context.Context) error {
-	// Nothing to close or release.
+	if c.conn != nil && !c.sharedConn {
+		c.conn.close()
+	}
 	return nil
 }
 
 // Helper functions
 
-// isNotFound return whether the error is a 404 - Not Found.
+// isForbidden returns whether the error is a 403 - Forbidden.
+func isForbidden(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusForbidden
+}
+
+// isAlreadyExists returns whether PrivX refused to create a secret because the
+// name is in use.
+func isAlreadyExists(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusConflict
+}
+
+// isNotFound returns whether PrivX reported that the secret does not exist, at any
+// depth of the error chain.
+//
+// A 404 alone does not tell, as a wrong apiBasePath or a proxy answers 404 as well:
+// the error must also say the secret was not found. A StatusError must have a 404 status.
 func isNotFound(err error) bool {
-	// PrivX loses the HTTP code so we need to test the error message
+	if err == nil {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		if statusErr.StatusCode != http.StatusNotFound {
+			return false
+		}
+		if statusErr.Body != nil && statusErr.Body.ErrorCode == "SECRET_NOT_FOUND" {
+			return true
+		}
+		err = statusErr.Err
+	}
+
+	// The SDK connector loses the HTTP code, so only the message tells. The innermost
+	// error is tested, as wrapping errors may quote names or properties of their own.
+	for inner := errors.Unwrap(err); inner != nil; inner = errors.Unwrap(inner) {
+		err = inner
+	}
 	return strings.Contains(strings.ToLower(err.Error()), "secret not found")
 }
 
+// isOwnerRejected returns whether PrivX refused the owner_id of a secret request.
+//
+// PrivX names the offending property in the error body of a 400 or 422 response.
+// The error message is not searched, as it may quote secret names or wrapped errors.
+func isOwnerRejected(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	switch statusErr.StatusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return hasProperty(statusErr.Body, "owner_id")
+	default:
+		return false
+	}
+}
+
 // decode decodes a secret value according to DecodingStrategy
 //
 // See https://external-secrets.io/latest/guides/decoding-strategy/
@@ -378,8 +993,36 @@ func anyToBytes(v any) ([]byte, error) {
 
 	default:
 		// For objects/arrays (map/slice) and other types: return JSON encoding
-		return json.Marshal(t)
+		b, err := json.Marshal(t)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrValueNotSerializable, err)
+		}
+		return b, nil
+	}
+}
+
+// marshalData returns the whole data of the named secret as a JSON object.
+//
+// The output is stable: encoding/json writes the keys of every map, nested ones
+// included, in sorted order, so unchanged data never churns the synced Secret.
+// When marshaling fails, the error names the first offending top-level key.
+func marshalData(secretName string, data map[string]interface{}) ([]byte, error) {
+	b, err := json.Marshal(data)
+	if err == nil {
+		return b, nil
+	}
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, kerr := json.Marshal(data[k]); kerr != nil {
+			return nil, fmt.Errorf("secret %q key %q: %w: %w", secretName, k, ErrValueNotSerializable, kerr)
+		}
 	}
+	return nil, fmt.Errorf("secret %q: %w: %w", secretName, ErrValueNotSerializable, err)
 }
 
 // rawToBytes converts a json.RawMessage into a byte slice suitable for secret return values.
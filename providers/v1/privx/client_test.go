@@ -0,0 +1,817 @@
+package privx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/vault"
+	privxapi "github.com/SSHcom/privx-sdk-go/v2/restapi"
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/apis/externalsecrets/v1alpha1"
+	testingfake "github.com/external-secrets/external-secrets/runtime/testing/fake"
+	"golang.org/x/sync/singleflight"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestClient(v *fakeVault) *SecretsClient {
+	return &SecretsClient{
+		vault:             v,
+		namespace:         "default",
+		defaultReadRoles:  []string{"read-role"},
+		defaultWriteRoles: []string{"write-role"},
+	}
+}
+
+func testSecret(data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "source", Namespace: "default"},
+		Data:       data,
+	}
+}
+
+func pushMetadata(spec string) *apiextensionsv1.JSON {
+	return &apiextensionsv1.JSON{Raw: []byte(`{"apiVersion":"kubernetes.external-secrets.io/v1alpha1","kind":"PushSecretMetadata","spec":` + spec + `}`)}
+}
+
+func TestPushSecretOwner(t *testing.T) {
+	serverErr := &StatusError{
+		StatusCode: http.StatusInternalServerError,
+		Err:        errors.New("error: INTERNAL_ERROR, property: owner_id"),
+		Body:       &privxapi.ErrorResponse{ErrorCode: "INTERNAL_ERROR", Property: "owner_id"},
+	}
+
+	tests := []struct {
+		name      string
+		metadata  *apiextensionsv1.JSON
+		createErr error
+		wantOwner string
+		wantErr   error
+	}{
+		{
+			name:      "owner from metadata",
+			metadata:  pushMetadata(`{"owner":"8f7d5a39-2d1b-4c1e-9a57-1f3c2b0e4d61"}`),
+			wantOwner: "8f7d5a39-2d1b-4c1e-9a57-1f3c2b0e4d61",
+		},
+		{
+			name:      "no metadata keeps default owner",
+			wantOwner: "",
+		},
+		{
+			name:      "metadata without owner keeps default owner",
+			metadata:  pushMetadata(`{}`),
+			wantOwner: "",
+		},
+		{
+			name:     "owner rejected by PrivX",
+			metadata: pushMetadata(`{"owner":"someone-else"}`),
+			createErr: &StatusError{
+				StatusCode: http.StatusBadRequest,
+				Err:        errors.New("error: INVALID_PARAMETER, property: owner_id"),
+				Body:       &privxapi.ErrorResponse{ErrorCode: "INVALID_PARAMETER", Property: "owner_id"},
+			},
+			wantOwner: "someone-else",
+			wantErr:   ErrOwnerRejected,
+		},
+		{
+			name:     "owner rejected in details",
+			metadata: pushMetadata(`{"owner":"someone-else"}`),
+			createErr: &StatusError{
+				StatusCode: http.StatusUnprocessableEntity,
+				Err:        errors.New("error: INVALID_BODY"),
+				Body: &privxapi.ErrorResponse{ErrorCode: "INVALID_BODY", Details: []privxapi.ErrorDetail{
+					{ErrorCode: "INVALID_PARAMETER", Property: "owner_id"},
+				}},
+			},
+			wantOwner: "someone-else",
+			wantErr:   ErrOwnerRejected,
+		},
+		{
+			name:      "owner_id quoted by another error",
+			metadata:  pushMetadata(`{"owner":"someone-else"}`),
+			createErr: fmt.Errorf("secret %q: %w", "owner_id", ErrConflict),
+			wantOwner: "someone-else",
+			wantErr:   ErrConflict,
+		},
+		{
+			name:      "other status naming owner_id",
+			metadata:  pushMetadata(`{"owner":"someone-else"}`),
+			createErr: serverErr,
+			wantOwner: "someone-else",
+			wantErr:   serverErr,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := newFakeVault()
+			v.createErr = tc.createErr
+			c := newTestClient(v)
+
+			err := c.PushSecret(context.Background(), testSecret(map[string][]byte{"password": []byte("s3cr3t")}), testingfake.PushSecretData{
+				SecretKey: "password",
+				RemoteKey: "app-secret",
+				Metadata:  tc.metadata,
+			})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("PushSecret() error = %v, want %v", err, tc.wantErr)
+			}
+			if rejected := errors.Is(err, ErrOwnerRejected); rejected != errors.Is(tc.wantErr, ErrOwnerRejected) {
+				t.Errorf("PushSecret() error = %v, owner rejected = %v", err, rejected)
+			}
+			if len(v.created) != 1 {
+				t.Fatalf("expected one create request, got %d", len(v.created))
+			}
+			if got := v.created[0].OwnerID; got != tc.wantOwner {
+				t.Errorf("OwnerID = %q, want %q", got, tc.wantOwner)
+			}
+		})
+	}
+}
+
+func TestPushSecretRolesForbidden(t *testing.T) {
+	v := newFakeVault()
+	v.createErr = &StatusError{StatusCode: http.StatusForbidden, Err: errors.New("error: FORBIDDEN")}
+	c := newTestClient(v)
+
+	err := c.PushSecret(context.Background(), testSecret(map[string][]byte{"password": []byte("s3cr3t")}), testingfake.PushSecretData{
+		SecretKey: "password",
+		RemoteKey: "app-secret",
+	})
+	if !errors.Is(err, ErrRolesForbidden) {
+		t.Fatalf("PushSecret() error = %v, want %v", err, ErrRolesForbidden)
+	}
+	for _, want := range []string{"app-secret", "read-role", "write-role"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("PushSecret() error = %q, want it to name %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "s3cr3t") {
+		t.Errorf("PushSecret() error leaks the secret value: %q", err)
+	}
+
+	// Other failures are not reported as role errors
+	v.createErr = &StatusError{StatusCode: http.StatusBadRequest, Err: errors.New("error: INVALID_BODY")}
+	err = c.PushSecret(context.Background(), testSecret(map[string][]byte{"password": []byte("s3cr3t")}), testingfake.PushSecretData{
+		SecretKey: "password",
+		RemoteKey: "app-secret",
+	})
+	if err == nil || errors.Is(err, ErrRolesForbidden) {
+		t.Errorf("PushSecret() error = %v, want a non-role error", err)
+	}
+}
+
+func TestPushSecretVerifyAfterWrite(t *testing.T) {
+	tests := []struct {
+		name              string
+		verify            bool
+		referenceCounting bool
+		dropWrites        bool
+		wantErr           error
+		wantReads         int
+	}{
+		// A push reads the secret once to tell a create from an update
+		{name: "verified", verify: true, wantReads: 2},
+		{name: "dropped write detected", verify: true, dropWrites: true, wantErr: ErrWriteNotVerified, wantReads: 2},
+		{name: "dropped update detected", verify: true, referenceCounting: true, dropWrites: true, wantErr: ErrWriteNotVerified, wantReads: 2},
+		{name: "off by default", dropWrites: true, wantReads: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := newFakeVault()
+			if tc.referenceCounting {
+				// An existing secret makes the push an update
+				v.secrets["app-secret"] = &vault.Secret{SecretRequest: vault.SecretRequest{
+					Name: "app-secret",
+					Data: &map[string]interface{}{"password": "old"},
+				}}
+			}
+			v.dropWrites = tc.dropWrites
+			c := newTestClient(v)
+			c.verifyAfterWrite = tc.verify
+			c.referenceCounting = tc.referenceCounting
+
+			err := c.PushSecret(context.Background(), testSecret(map[string][]byte{"password": []byte("s3cr3t")}), testingfake.PushSecretData{
+				SecretKey: "password",
+				RemoteKey: "app-secret",
+			})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("PushSecret() error = %v, want %v", err, tc.wantErr)
+			}
+			if v.getCalls != tc.wantReads {
+				t.Errorf("read %d times, want %d", v.getCalls, tc.wantReads)
+			}
+		})
+	}
+}
+
+func TestPushSecretInvalidMetadata(t *testing.T) {
+	v := newFakeVault()
+	c := newTestClient(v)
+
+	err := c.PushSecret(context.Background(), testSecret(map[string][]byte{"password": []byte("s3cr3t")}), testingfake.PushSecretData{
+		SecretKey: "password",
+		RemoteKey: "app-secret",
+		Metadata:  &apiextensionsv1.JSON{Raw: []byte(`{"kind":"Unknown"}`)},
+	})
+	if err == nil {
+		t.Fatal("expected an error for invalid metadata")
+	}
+	if len(v.created) != 0 {
+		t.Errorf("expected no create request, got %d", len(v.created))
+	}
+}
+
+func TestGetSecretProperty(t *testing.T) {
+	v := newFakeVault(fakeSecret("app", map[string]interface{}{
+		"user": "admin",
+		"db":   map[string]interface{}{"host": "db.example.com", "port": float64(5432)},
+	}))
+	c := newTestClient(v)
+
+	tests := []struct {
+		property string
+		want     string
+		wantErr  error
+	}{
+		{property: "user", want: "admin"},
+		{property: "db.host", want: "db.example.com"},
+		{property: "/db/port", want: "5432"},
+		{property: "db", want: `{"host":"db.example.com","port":5432}`},
+		{property: "db.nope", wantErr: ErrPropertyNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.property, func(t *testing.T) {
+			got, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "app", Property: tc.property})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("GetSecret() error = %v, want %v", err, tc.wantErr)
+			}
+			if string(got) != tc.want {
+				t.Errorf("GetSecret() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetSecretRejectObjectProperty(t *testing.T) {
+	v := newFakeVault(fakeSecret("app", map[string]interface{}{
+		"user": "admin",
+		"db":   map[string]interface{}{"host": "db.example.com"},
+	}))
+
+	tests := []struct {
+		name     string
+		reject   bool
+		property string
+		want     string
+		wantErr  error
+	}{
+		{name: "scalar", reject: true, property: "user", want: "admin"},
+		{name: "object by default", property: "db", want: `{"host":"db.example.com"}`},
+		{name: "object rejected", reject: true, property: "db", wantErr: ErrObjectProperty},
+		{name: "list still an object", reject: true, property: "user,db.host", want: `{"db.host":"db.example.com","user":"admin"}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestClient(v)
+			c.rejectObjectProperty = tc.reject
+
+			got, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "app", Property: tc.property})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("GetSecret() error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil && !strings.Contains(err.Error(), "dataFrom.extract") {
+				t.Errorf("GetSecret() error = %q, want a hint to dataFrom.extract", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("GetSecret() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetSecretMapProperty(t *testing.T) {
+	v := newFakeVault(fakeSecret("app", map[string]interface{}{
+		"user": "admin",
+		"db":   map[string]interface{}{"host": "db.example.com", "opts": map[string]interface{}{"ssl": true}},
+	}))
+	c := newTestClient(v)
+
+	tests := []struct {
+		property string
+		want     map[string][]byte
+		wantErr  error
+	}{
+		{property: "user", want: map[string][]byte{"user": []byte("admin")}},
+		{property: "db.opts", want: map[string][]byte{"ssl": []byte("true")}},
+		{property: "/db/host", want: map[string][]byte{"/db/host": []byte("db.example.com")}},
+		{property: "db.nope", wantErr: ErrPropertyNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.property, func(t *testing.T) {
+			got, err := c.GetSecretMap(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "app", Property: tc.property})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("GetSecretMap() error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr == nil && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("GetSecretMap() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValueNotSerializable(t *testing.T) {
+	v := newFakeVault(fakeSecret("app", map[string]interface{}{
+		"user": "admin",
+		"bad":  make(chan int),
+		"db":   map[string]interface{}{"bad": func() {}},
+	}))
+	c := newTestClient(v)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		call    func() error
+		wantKey string
+	}{
+		{
+			name: "GetSecret property",
+			call: func() error {
+				_, err := c.GetSecret(ctx, esv1.ExternalSecretDataRemoteRef{Key: "app", Property: "bad"})
+				return err
+			},
+			wantKey: `key "bad"`,
+		},
+		{
+			name: "GetSecret whole object",
+			call: func() error {
+				_, err := c.GetSecret(ctx, esv1.ExternalSecretDataRemoteRef{Key: "app"})
+				return err
+			},
+			wantKey: `key "bad"`,
+		},
+		{
+			name: "GetSecretMap",
+			call: func() error {
+				_, err := c.GetSecretMap(ctx, esv1.ExternalSecretDataRemoteRef{Key: "app", Property: "db"})
+				return err
+			},
+			wantKey: `key "db.bad"`,
+		},
+		{
+			name: "GetAllSecrets",
+			call: func() error {
+				_, err := c.GetAllSecrets(ctx, esv1.ExternalSecretFind{ConversionStrategy: esv1.ExternalSecretConversionDefault})
+				return err
+			},
+			wantKey: `key "bad"`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call()
+			if !errors.Is(err, ErrValueNotSerializable) {
+				t.Fatalf("error = %v, want ErrValueNotSerializable", err)
+			}
+			if !strings.Contains(err.Error(), `secret "app"`) || !strings.Contains(err.Error(), tc.wantKey) {
+				t.Errorf("error %q does not name secret %q and %s", err, "app", tc.wantKey)
+			}
+		})
+	}
+}
+
+// largeVault returns a fake vault with n secrets, every tenth named "app-*".
+func largeVault(n int) *fakeVault {
+	v := newFakeVault()
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("other-%06d", i)
+		if i%10 == 0 {
+			name = fmt.Sprintf("app-%06d", i)
+		}
+		v.secrets[name] = &vault.Secret{SecretRequest: vault.SecretRequest{
+			Name: name,
+			Data: &map[string]interface{}{"index": float64(i)},
+		}}
+	}
+	return v
+}
+
+func TestGetAllSecretsPaging(t *testing.T) {
+	tests := []struct {
+		secrets   int
+		wantPages int
+	}{
+		{secrets: 0, wantPages: 1},
+		{secrets: 99, wantPages: 1},
+		{secrets: 100, wantPages: 2},
+		{secrets: 101, wantPages: 2},
+		{secrets: 25050, wantPages: 251},
+	}
+
+	for _, tc := range tests {
+		t.Run(strconv.Itoa(tc.secrets), func(t *testing.T) {
+			v := largeVault(tc.secrets)
+			c := newTestClient(v)
+
+			got, err := c.GetAllSecrets(context.Background(), esv1.ExternalSecretFind{
+				Name:               &esv1.FindName{RegExp: "^app-"},
+				ConversionStrategy: esv1.ExternalSecretConversionDefault,
+			})
+			if err != nil {
+				t.Fatalf("GetAllSecrets() error = %v", err)
+			}
+			if want := (tc.secrets + 9) / 10; len(got) != want {
+				t.Errorf("GetAllSecrets() returned %d secrets, want %d", len(got), want)
+			}
+			if v.listCalls != tc.wantPages {
+				t.Errorf("listed %d pages, want %d", v.listCalls, tc.wantPages)
+			}
+			if v.getCalls != len(got) {
+				t.Errorf("fetched %d secrets, want %d", v.getCalls, len(got))
+			}
+		})
+	}
+}
+
+func TestGetAllSecretsListData(t *testing.T) {
+	for _, listData := range []bool{false, true} {
+		t.Run(strconv.FormatBool(listData), func(t *testing.T) {
+			v := newFakeVault(
+				fakeSecret("app-db", map[string]interface{}{"password": "s3cr3t"}),
+				fakeSecret("app-api", map[string]interface{}{"token": "t0k3n"}),
+			)
+			v.listData = listData
+			c := newTestClient(v)
+
+			got, err := c.GetAllSecrets(context.Background(), esv1.ExternalSecretFind{
+				Name:               &esv1.FindName{RegExp: "^app-"},
+				ConversionStrategy: esv1.ExternalSecretConversionDefault,
+			})
+			if err != nil {
+				t.Fatalf("GetAllSecrets() error = %v", err)
+			}
+			want := map[string][]byte{
+				"app-db":  []byte(`{"password":"s3cr3t"}`),
+				"app-api": []byte(`{"token":"t0k3n"}`),
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("GetAllSecrets() = %s, want %s", got, want)
+			}
+
+			wantGets := len(want)
+			if listData {
+				wantGets = 0
+			}
+			if v.getCalls != wantGets {
+				t.Errorf("fetched %d secrets, want %d", v.getCalls, wantGets)
+			}
+		})
+	}
+}
+
+func BenchmarkGetAllSecrets(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			c := newTestClient(largeVault(n))
+			find := esv1.ExternalSecretFind{
+				Name:               &esv1.FindName{RegExp: "^app-"},
+				ConversionStrategy: esv1.ExternalSecretConversionDefault,
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.GetAllSecrets(context.Background(), find); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestOperationTimeouts(t *testing.T) {
+	v := newFakeVault(fakeSecret("app", map[string]interface{}{"password": "s3cr3t"}))
+	c := newTestClient(v)
+	c.requestTimeout = time.Second
+	c.findTimeout = time.Hour
+
+	var remaining time.Duration
+	c.newVault = func(ctx context.Context) vaultClient {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			t.Fatal("vault bound to a context without deadline")
+		}
+		remaining = time.Until(deadline)
+		return v
+	}
+
+	if _, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "app"}); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if remaining > time.Second {
+		t.Errorf("GetSecret() deadline in %v, want at most the request timeout", remaining)
+	}
+
+	if _, err := c.GetAllSecrets(context.Background(), esv1.ExternalSecretFind{
+		ConversionStrategy: esv1.ExternalSecretConversionDefault,
+	}); err != nil {
+		t.Fatalf("GetAllSecrets() error = %v", err)
+	}
+	if remaining <= time.Second {
+		t.Errorf("GetAllSecrets() deadline in %v, want the find timeout", remaining)
+	}
+}
+
+func TestWholeSecretOutputStable(t *testing.T) {
+	data := map[string]interface{}{}
+	for i := 0; i < 50; i++ {
+		data[fmt.Sprintf("key-%02d", i)] = map[string]interface{}{
+			"z": i,
+			"a": []interface{}{map[string]interface{}{"y": "1", "b": "2"}},
+			"m": "v",
+		}
+	}
+	v := newFakeVault(fakeSecret("app", data))
+	c := newTestClient(v)
+	ctx := context.Background()
+	find := esv1.ExternalSecretFind{ConversionStrategy: esv1.ExternalSecretConversionDefault}
+
+	first, err := c.GetSecret(ctx, esv1.ExternalSecretDataRemoteRef{Key: "app"})
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if !strings.HasPrefix(string(first), `{"key-00":{"a":[{"b":"2","y":"1"}],"m":"v","z":0},"key-01":`) {
+		t.Errorf("GetSecret() keys not sorted: %.80s", first)
+	}
+	firstAll, err := c.GetAllSecrets(ctx, find)
+	if err != nil {
+		t.Fatalf("GetAllSecrets() error = %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		got, err := c.GetSecret(ctx, esv1.ExternalSecretDataRemoteRef{Key: "app"})
+		if err != nil {
+			t.Fatalf("GetSecret() error = %v", err)
+		}
+		if string(got) != string(first) {
+			t.Fatalf("GetSecret() output changed between reads")
+		}
+		all, err := c.GetAllSecrets(ctx, find)
+		if err != nil {
+			t.Fatalf("GetAllSecrets() error = %v", err)
+		}
+		if string(all["app"]) != string(firstAll["app"]) {
+			t.Fatalf("GetAllSecrets() output changed between reads")
+		}
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	status404 := &StatusError{
+		StatusCode: http.StatusNotFound,
+		Err:        errors.New("error: SECRET_NOT_FOUND, message: secret not found"),
+		Body:       &privxapi.ErrorResponse{ErrorCode: "SECRET_NOT_FOUND", ErrorMessage: "secret not found"},
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil},
+		{name: "message", err: errFakeNotFound, want: true},
+		{name: "wrapped once", err: fmt.Errorf("secret %q: %w", "app", errFakeNotFound), want: true},
+		{name: "wrapped twice", err: fmt.Errorf("retry: %w", fmt.Errorf("secret %q: %w", "app", errFakeNotFound)), want: true},
+		{name: "status wrapped twice", err: fmt.Errorf("retry: %w", fmt.Errorf("get: %w", status404)), want: true},
+		{name: "status with message only", err: &StatusError{StatusCode: http.StatusNotFound, Err: errFakeNotFound}, want: true},
+		{
+			name: "status of a wrong route",
+			err:  &StatusError{StatusCode: http.StatusNotFound, Err: errors.New("HTTP error: 404 Not Found")},
+		},
+		{
+			name: "status of another missing object",
+			err: &StatusError{
+				StatusCode: http.StatusNotFound,
+				Err:        errors.New("error: NOT_FOUND"),
+				Body:       &privxapi.ErrorResponse{ErrorCode: "NOT_FOUND"},
+			},
+		},
+		{
+			name: "other status quoting not found",
+			err:  &StatusError{StatusCode: http.StatusInternalServerError, Err: errors.New("secret not found in cache")},
+		},
+		{name: "name quoted by a wrapper", err: fmt.Errorf("secret %q: %w", "secret not found", ErrConflict)},
+		{name: "other error", err: ErrConnect},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isNotFound(tc.err); got != tc.want {
+				t.Errorf("isNotFound(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWrappedNotFound(t *testing.T) {
+	wrapped := fmt.Errorf("retry: %w", fmt.Errorf("secret %q: %w", "app", errFakeNotFound))
+	ctx := context.Background()
+
+	v := newFakeVault()
+	v.getErr = wrapped
+	v.metadataErr = wrapped
+	v.deleteErr = wrapped
+	c := newTestClient(v)
+
+	exists, err := c.SecretExists(ctx, v1alpha1.PushSecretRemoteRef{RemoteKey: "app"})
+	if err != nil || exists {
+		t.Errorf("SecretExists() = %v, %v, want false, nil", exists, err)
+	}
+	if err := c.DeleteSecret(ctx, v1alpha1.PushSecretRemoteRef{RemoteKey: "app"}); err != nil {
+		t.Errorf("DeleteSecret() error = %v", err)
+	}
+}
+
+func TestRequireProperty(t *testing.T) {
+	v := newFakeVault(fakeSecret("app", map[string]interface{}{"user": "admin"}))
+	ctx := context.Background()
+
+	for _, require := range []bool{false, true} {
+		c := newTestClient(v)
+		c.requireProperty = require
+		var wantErr error
+		if require {
+			wantErr = ErrPropertyRequired
+		}
+
+		if _, err := c.GetSecret(ctx, esv1.ExternalSecretDataRemoteRef{Key: "app"}); !errors.Is(err, wantErr) {
+			t.Errorf("requireProperty %v: GetSecret() error = %v, want %v", require, err, wantErr)
+		}
+		if _, err := c.GetSecretMap(ctx, esv1.ExternalSecretDataRemoteRef{Key: "app"}); !errors.Is(err, wantErr) {
+			t.Errorf("requireProperty %v: GetSecretMap() error = %v, want %v", require, err, wantErr)
+		}
+		if _, err := c.GetSecret(ctx, esv1.ExternalSecretDataRemoteRef{Key: "app", Property: "user"}); err != nil {
+			t.Errorf("requireProperty %v: GetSecret(property) error = %v", require, err)
+		}
+		if _, err := c.GetSecretMap(ctx, esv1.ExternalSecretDataRemoteRef{Key: "app", Property: "user"}); err != nil {
+			t.Errorf("requireProperty %v: GetSecretMap(property) error = %v", require, err)
+		}
+	}
+}
+
+func TestSecretExistsRequireProperty(t *testing.T) {
+	// The existence of a secret does not depend on the read options of the store
+	v := newFakeVault(fakeSecret("app", map[string]interface{}{"v": strings.Repeat("x", 100)}))
+	c := newTestClient(v)
+	c.requireProperty = true
+	c.maxSecretSize = 10
+	c.keys, _ = newKeyPolicy(nil, []string{"^app$"})
+
+	exists, err := c.SecretExists(context.Background(), v1alpha1.PushSecretRemoteRef{RemoteKey: "app"})
+	if err != nil || !exists {
+		t.Errorf("SecretExists() = %v, %v, want true", exists, err)
+	}
+	if v.getCalls != 0 {
+		t.Errorf("SecretExists() fetched the secret data %d times, want none", v.getCalls)
+	}
+
+	exists, err = c.SecretExists(context.Background(), v1alpha1.PushSecretRemoteRef{RemoteKey: "missing"})
+	if err != nil || exists {
+		t.Errorf("SecretExists(missing) = %v, %v, want false", exists, err)
+	}
+}
+
+// blockingVault holds GetSecret calls until release is closed.
+type blockingVault struct {
+	vaultClient
+	calls   atomic.Int32
+	arrived chan struct{}
+	release chan struct{}
+}
+
+func (v *blockingVault) GetSecret(secretName string) (*vault.Secret, error) {
+	if v.calls.Add(1) == 1 {
+		close(v.arrived)
+	}
+	<-v.release
+	return v.vaultClient.GetSecret(secretName)
+}
+
+func TestGetSecretCoalesced(t *testing.T) {
+	const readers = 20
+	v := &blockingVault{
+		vaultClient: newFakeVault(fakeSecret("app", map[string]interface{}{"password": "s3cr3t"})),
+		arrived:     make(chan struct{}),
+		release:     make(chan struct{}),
+	}
+	reads := &singleflight.Group{}
+
+	var started, done sync.WaitGroup
+	errs := make(chan error, readers)
+	for range readers {
+		started.Add(1)
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			// A client per reconcile, sharing the reads of the connector
+			c := &SecretsClient{vault: v, reads: reads}
+			started.Done()
+			got, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "app", Property: "password"})
+			if err == nil && string(got) != "s3cr3t" {
+				err = fmt.Errorf("GetSecret() = %q", got)
+			}
+			errs <- err
+		}()
+	}
+
+	started.Wait()
+	<-v.arrived
+	// Let every reader join the request in flight
+	time.Sleep(100 * time.Millisecond)
+	close(v.release)
+	done.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if calls := v.calls.Load(); calls != 1 {
+		t.Errorf("vault GetSecret calls = %d, want 1", calls)
+	}
+}
+
+// boundVault fails GetSecret calls whose context ended meanwhile, as the PrivX
+// client does.
+type boundVault struct {
+	vaultClient
+	ctx context.Context
+}
+
+func (v boundVault) GetSecret(secretName string) (*vault.Secret, error) {
+	secret, err := v.vaultClient.GetSecret(secretName)
+	if v.ctx.Err() != nil {
+		return nil, v.ctx.Err()
+	}
+	return secret, err
+}
+
+func TestGetSecretCoalescedCancel(t *testing.T) {
+	v := &blockingVault{
+		vaultClient: newFakeVault(fakeSecret("app", map[string]interface{}{"password": "s3cr3t"})),
+		arrived:     make(chan struct{}),
+		release:     make(chan struct{}),
+	}
+	reads := &singleflight.Group{}
+	newClient := func() *SecretsClient {
+		return &SecretsClient{
+			vault:    v,
+			newVault: func(ctx context.Context) vaultClient { return boundVault{vaultClient: v, ctx: ctx} },
+			reads:    reads,
+		}
+	}
+	ref := esv1.ExternalSecretDataRemoteRef{Key: "app", Property: "password"}
+
+	// The first caller starts the shared request and gives up
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := newClient().GetSecret(ctx, ref)
+		first <- err
+	}()
+	<-v.arrived
+
+	second := make(chan error, 1)
+	go func() {
+		got, err := newClient().GetSecret(context.Background(), ref)
+		if err == nil && string(got) != "s3cr3t" {
+			err = fmt.Errorf("GetSecret() = %q", got)
+		}
+		second <- err
+	}()
+	// Let the second caller join the request in flight
+	time.Sleep(100 * time.Millisecond)
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("first GetSecret() error = %v, want %v", err, context.Canceled)
+	}
+	close(v.release)
+	if err := <-second; err != nil {
+		t.Errorf("second GetSecret() error = %v", err)
+	}
+	if calls := v.calls.Load(); calls != 1 {
+		t.Errorf("vault GetSecret calls = %d, want 1", calls)
+	}
+}
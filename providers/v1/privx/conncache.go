@@ -0,0 +1,129 @@
+/*
+Share PrivX connectors between the clients of a store.
+*/
+
+package privx
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+// maxCachedConnectors bounds the number of connectors kept by the provider.
+const maxCachedConnectors = 64
+
+// connectorCache keeps the connectors of stores authenticating with OAuth
+// credentials, so that the clients created for every reconcile reuse their TLS
+// connections and access token instead of opening new ones.
+//
+// Connectors are keyed by a hash of the credentials and of every setting shaping
+// the connector, see connectorKey. A store whose key changes, e.g. because its
+// credentials were rotated, drops its previous connector, and the least recently
+// used connector is dropped when more than max are kept. A dropped connector
+// closes its idle connections; clients still holding it keep working.
+type connectorCache struct {
+	max int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // by key, the values are *cachedConnector
+	lru     *list.List               // most recently used first
+	stores  map[string]string        // keys by store, see storeIdentity
+}
+
+type cachedConnector struct {
+	key  string
+	conn *connector
+}
+
+func newConnectorCache(max int) *connectorCache {
+	return &connectorCache{
+		max:     max,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+		stores:  map[string]string{},
+	}
+}
+
+// get returns the connector of key for store, creating it with build when missing.
+// build is called with the cache locked and must not block.
+func (c *connectorCache) get(store, key string, build func() (*connector, error)) (*connector, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if previous, ok := c.stores[store]; ok && previous != key {
+		c.drop(previous)
+	}
+
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		c.stores[store] = key
+		return e.Value.(*cachedConnector).conn, nil
+	}
+
+	conn, err := build()
+	if err != nil {
+		return nil, err
+	}
+	c.entries[key] = c.lru.PushFront(&cachedConnector{key: key, conn: conn})
+	c.stores[store] = key
+	for c.lru.Len() > c.max {
+		c.drop(c.lru.Back().Value.(*cachedConnector).key)
+	}
+	return conn, nil
+}
+
+// drop removes the connector of key, and the stores using it, from the cache.
+func (c *connectorCache) drop(key string) {
+	e, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	c.lru.Remove(e)
+	delete(c.entries, key)
+	e.Value.(*cachedConnector).conn.close()
+
+	for store, k := range c.stores {
+		if k == key {
+			delete(c.stores, store)
+		}
+	}
+}
+
+// connectorKey returns the cache key of a connector authenticating with creds.
+// Credentials only enter the key hashed.
+func connectorKey(privxSpec *esv1.PrivxProvider, creds *oauthCredentials) string {
+	// Marshalling a struct of strings, ints and durations cannot fail
+	b, _ := json.Marshal(struct {
+		Host                string
+		APIBasePath         string
+		AuthPath            string
+		MaxIdleConns        *int
+		MaxIdleConnsPerHost *int
+		IdleConnTimeout     *metav1.Duration
+		Credentials         [4]string
+	}{
+		Host:                privxSpec.Host,
+		APIBasePath:         privxSpec.APIBasePath,
+		AuthPath:            privxSpec.AuthPath,
+		MaxIdleConns:        privxSpec.MaxIdleConns,
+		MaxIdleConnsPerHost: privxSpec.MaxIdleConnsPerHost,
+		IdleConnTimeout:     privxSpec.IdleConnTimeout,
+		Credentials:         [4]string{creds.clientID, creds.clientSecret, creds.apiClientID, creds.apiClientSecret},
+	})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// storeIdentity identifies the store of a client. Cluster stores read their
+// credentials relative to the client namespace, so it is part of the identity.
+func storeIdentity(store esv1.GenericStore, namespace string) string {
+	return strings.Join([]string{store.GetKind(), store.GetNamespace(), store.GetName(), namespace}, "/")
+}
@@ -0,0 +1,87 @@
+package privx
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNewClientSharesConnector(t *testing.T) {
+	credentials := kubeSecret("privx-credentials", map[string]string{
+		"clientID":        "oauth-id",
+		"clientSecret":    "oauth-secret",
+		"apiClientID":     "api-id",
+		"apiClientSecret": "api-secret",
+	})
+	kube := clientfake.NewClientBuilder().WithObjects(credentials).Build()
+	store := testStore(&esv1.PrivxProvider{
+		Host: "https://privx.example.com",
+		Auth: &esv1.PrivXAuth{OAuth: &esv1.PrivXOAuth{
+			CredentialsSecretRef: &esv1.PrivXCredentialsSecretRef{Name: "privx-credentials"},
+		}},
+	})
+	p := NewProvider().(*Provider)
+	ctx := context.Background()
+
+	newConn := func() *connector {
+		t.Helper()
+		client, err := p.NewClient(ctx, store, kube, "default")
+		if err != nil {
+			t.Fatalf("NewClient() error = %v", err)
+		}
+		defer client.Close(ctx)
+		return client.(*SecretsClient).conn
+	}
+
+	first := newConn()
+	if second := newConn(); second != first {
+		t.Errorf("NewClient() with identical config created a new connector")
+	}
+
+	credentials.Data["apiClientSecret"] = []byte("rotated")
+	if err := kube.Update(ctx, credentials); err != nil {
+		t.Fatal(err)
+	}
+	if rotated := newConn(); rotated == first {
+		t.Errorf("NewClient() with rotated credentials reused the connector")
+	}
+	if n := len(p.connectors.entries); n != 1 {
+		t.Errorf("cached connectors = %d, want 1 after rotation", n)
+	}
+}
+
+func TestConnectorCacheBounded(t *testing.T) {
+	cache := newConnectorCache(2)
+	builds := 0
+	get := func(store, key string) *connector {
+		t.Helper()
+		conn, err := cache.get(store, key, func() (*connector, error) {
+			builds++
+			return newConnector("https://privx.example.com", nil, newTransport(&esv1.PrivxProvider{})), nil
+		})
+		if err != nil {
+			t.Fatalf("get() error = %v", err)
+		}
+		return conn
+	}
+
+	for i := range 3 {
+		get(fmt.Sprint("store", i), fmt.Sprint("key", i))
+	}
+	if len(cache.entries) != 2 || cache.lru.Len() != 2 {
+		t.Fatalf("cached connectors = %d, want 2", len(cache.entries))
+	}
+	if _, ok := cache.stores["store0"]; ok {
+		t.Errorf("evicted connector still assigned to its store")
+	}
+
+	// key2 is kept, key0 was evicted and is built again
+	get("store2", "key2")
+	get("store0", "key0")
+	if builds != 4 {
+		t.Errorf("builds = %d, want 4", builds)
+	}
+}
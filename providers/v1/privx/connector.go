@@ -0,0 +1,494 @@
+/*
+Implement the PrivX REST connector on a configurable HTTP transport.
+*/
+
+package privx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	privxapi "github.com/SSHcom/privx-sdk-go/v2/restapi"
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/go-logr/logr"
+	"golang.org/x/sync/singleflight"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Defaults for the HTTP transport, used when the store leaves them unset.
+const (
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 10
+	defaultIdleConnTimeout     = 90 * time.Second
+)
+
+// StatusError is returned for PrivX responses with an HTTP error status.
+type StatusError struct {
+	StatusCode int
+	Err        error
+
+	// RequestID is the ID PrivX assigned to the request, see requestIDHeaders.
+	// Empty when the response carried none.
+	RequestID string
+
+	// Body is the PrivX error of the response body, nil when the body is not
+	// one, e.g. the page of a proxy.
+	Body *privxapi.ErrorResponse
+}
+
+// errorBody parses a PrivX error response body, returning nil for other bodies.
+func errorBody(body []byte) *privxapi.ErrorResponse {
+	var response privxapi.ErrorResponse
+	if err := json.Unmarshal(body, &response); err != nil || response.ErrorCode == "" {
+		return nil
+	}
+	return &response
+}
+
+// hasProperty reports whether the PrivX error names property, itself or in its details.
+func hasProperty(body *privxapi.ErrorResponse, property string) bool {
+	if body == nil {
+		return false
+	}
+	if body.Property == property {
+		return true
+	}
+	for _, detail := range body.Details {
+		if detail.Property == property {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *StatusError) Error() string {
+	if e.RequestID != "" {
+		// The ID lets the PrivX vendor find the request in the server logs
+		return fmt.Sprintf("%s (request ID %s)", e.Err, e.RequestID)
+	}
+	return e.Err.Error()
+}
+
+// requestIDHeaders are the response headers that may carry the request ID,
+// in order of preference.
+var requestIDHeaders = []string{"X-Request-Id", "X-Correlation-Id"}
+
+// requestID returns the request ID of a response, or "" when it has none.
+func requestID(header http.Header) string {
+	for _, name := range requestIDHeaders {
+		if id := header.Get(name); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// Check during compile that we implement the interfaces.
+var (
+	_ privxapi.Connector = (*connector)(nil)
+	_ privxapi.Connector = tokenPathConnector{}
+	_ privxapi.CURL      = (*curl)(nil)
+)
+
+// oauthTokenPath is the OAuth token endpoint requested by the SDK authorizers.
+const oauthTokenPath = "/auth/api/v1/oauth/token"
+
+// tokenPathConnector sends the OAuth token requests of the SDK authorizers to
+// another path, the path being hard-coded in the SDK.
+type tokenPathConnector struct {
+	privxapi.Connector
+	path string
+}
+
+// withTokenPath returns conn requesting OAuth tokens from path, or conn itself
+// when path is empty.
+func withTokenPath(conn privxapi.Connector, path string) privxapi.Connector {
+	if path == "" {
+		return conn
+	}
+	return tokenPathConnector{Connector: conn, path: path}
+}
+
+func (c tokenPathConnector) URL(templatePath string, args ...interface{}) privxapi.CURL {
+	if templatePath == oauthTokenPath {
+		templatePath = c.path
+	}
+	return c.Connector.URL(templatePath, args...)
+}
+
+// connector is a PrivX REST API connector.
+//
+// The SDK connector does not allow configuring its HTTP transport, so connector
+// mirrors its request handling on a transport built from the store specification:
+// path arguments are escaped, relative paths are joined with the base URL and
+// request and response bodies are JSON. It replaces restapi.New until the SDK
+// accepts a transport, and is checked against the SDK on every upgrade.
+type connector struct {
+	baseURL   string
+	auth      privxapi.Authorizer
+	transport *http.Transport
+	http      *http.Client
+
+	// reads coalesces concurrent reads of the same secret by the clients sharing
+	// the connector, see SecretsClient.readSecret.
+	reads *singleflight.Group
+
+	// ctx bounds the requests, see withContext.
+	ctx context.Context
+}
+
+// newTransport creates the HTTP transport for PrivX connections.
+func newTransport(privxSpec *esv1.PrivxProvider) *http.Transport {
+	transport := &http.Transport{
+		ReadBufferSize: 128 * 1024,
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+		}).DialContext,
+		MaxIdleConns:        defaultMaxIdleConns,
+		MaxIdleConnsPerHost: defaultMaxIdleConnsPerHost,
+		IdleConnTimeout:     defaultIdleConnTimeout,
+	}
+	if privxSpec.MaxIdleConns != nil {
+		transport.MaxIdleConns = *privxSpec.MaxIdleConns
+	}
+	if privxSpec.MaxIdleConnsPerHost != nil {
+		transport.MaxIdleConnsPerHost = *privxSpec.MaxIdleConnsPerHost
+	}
+	if privxSpec.IdleConnTimeout != nil {
+		transport.IdleConnTimeout = privxSpec.IdleConnTimeout.Duration
+	}
+	return transport
+}
+
+// newConnector creates a connector to baseURL authenticating with auth.
+func newConnector(baseURL string, auth privxapi.Authorizer, transport *http.Transport) *connector {
+	return &connector{
+		baseURL:   baseURL,
+		auth:      auth,
+		transport: transport,
+		reads:     &singleflight.Group{},
+		http: &http.Client{
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// withContext returns a copy of the connector sending its requests with ctx.
+func (c *connector) withContext(ctx context.Context) *connector {
+	bound := *c
+	bound.ctx = ctx
+	return &bound
+}
+
+// close closes the idle connections of the transport.
+// Connections in use are closed once their requests complete.
+func (c *connector) close() {
+	c.transport.CloseIdleConnections()
+}
+
+// URL creates a request to an absolute URL or to a path relative to the base URL.
+//
+// String arguments are path escaped, so secret names may contain slashes, spaces
+// and URL reserved characters: "a/b c?" is requested as "a%2Fb%20c%3F".
+func (c *connector) URL(templatePath string, args ...interface{}) privxapi.CURL {
+	escapedArgs := make([]interface{}, len(args))
+	for i, arg := range args {
+		if str, ok := arg.(string); ok {
+			escapedArgs[i] = url.PathEscape(str)
+		} else {
+			escapedArgs[i] = arg
+		}
+	}
+
+	target := fmt.Sprintf(templatePath, escapedArgs...)
+	if len(target) > 0 && target[0] == '/' {
+		target = c.baseURL + target
+	}
+
+	return &curl{
+		conn:   c,
+		url:    target,
+		header: http.Header{},
+	}
+}
+
+// curl is a single request built by connector.
+type curl struct {
+	conn      *connector
+	url       string
+	header    http.Header
+	cookieJar http.CookieJar
+	payload   []byte
+	fail      error
+}
+
+// Query defines URI parameters of the request.
+func (r *curl) Query(data interface{}) privxapi.CURL {
+	params, err := encodeValues(data)
+	if r.fail = err; err == nil {
+		r.url = r.url + "?" + params.Encode()
+	}
+	return r
+}
+
+// Header defines a request header.
+func (r *curl) Header(head, value string) privxapi.CURL {
+	r.header.Add(head, value)
+	return r
+}
+
+// CookieJar sets the cookie jar for the request.
+func (r *curl) CookieJar(jar http.CookieJar) privxapi.CURL {
+	r.cookieJar = jar
+	return r
+}
+
+// Status requests the URL and discards the response.
+func (r *curl) Status(...int) (http.Header, error) {
+	return r.do(http.MethodGet, nil)
+}
+
+// Get fetches JSON content from the URL.
+func (r *curl) Get(in interface{}) (http.Header, error) {
+	return r.do(http.MethodGet, in)
+}
+
+// Put sends content to the URL.
+func (r *curl) Put(eg interface{}, in ...interface{}) (http.Header, error) {
+	r.send(eg)
+	return r.do(http.MethodPut, first(in))
+}
+
+// Post sends content to the URL.
+func (r *curl) Post(eg interface{}, in ...interface{}) (http.Header, error) {
+	if eg != nil {
+		r.send(eg)
+	}
+	return r.do(http.MethodPost, first(in))
+}
+
+// Delete removes content behind the URL.
+func (r *curl) Delete(in ...interface{}) (http.Header, error) {
+	return r.do(http.MethodDelete, first(in))
+}
+
+// Fetch returns the raw response body.
+func (r *curl) Fetch() ([]byte, error) {
+	_, body, err := r.roundTrip(http.MethodGet)
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// Download is not used by the provider.
+func (r *curl) Download(string) error {
+	return fmt.Errorf("download: %w", ErrNotImplemented)
+}
+
+// send encodes the request payload according to the Content-Type header.
+func (r *curl) send(data interface{}) {
+	if r.fail != nil {
+		return
+	}
+
+	if r.header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		params, err := encodeValues(data)
+		if r.fail = err; err == nil {
+			r.payload = []byte(params.Encode())
+		}
+		return
+	}
+
+	r.header.Set("Content-Type", "application/json")
+	r.payload, r.fail = json.Marshal(data)
+}
+
+// do sends the request and decodes a JSON response into out, when given.
+func (r *curl) do(method string, out interface{}) (http.Header, error) {
+	resp, body, err := r.roundTrip(method)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		err := privxapi.ErrorFromResponse(resp, body)
+		if resp.StatusCode == http.StatusUnauthorized {
+			// Still unauthorized after retrying, see roundTrip
+			err = fmt.Errorf("%w: %w", ErrAuthFailed, err)
+		}
+		id := requestID(resp.Header)
+		r.logger().V(logDebug).Info("PrivX request failed",
+			"method", method, "status", resp.StatusCode, "requestID", id)
+		return nil, &StatusError{
+			StatusCode: resp.StatusCode,
+			Err:        err,
+			RequestID:  id,
+			Body:       errorBody(body),
+		}
+	}
+	if out != nil {
+		if err := json.Unmarshal(body, out); err != nil {
+			return nil, err
+		}
+	}
+	return resp.Header, nil
+}
+
+// logger returns the logger of the request context.
+func (r *curl) logger() logr.Logger {
+	if r.conn.ctx == nil {
+		return log.Log
+	}
+	return log.FromContext(r.conn.ctx)
+}
+
+// tokenRenewer is implemented by authorizers that can drop their cached access
+// token, so that the next request obtains a new one.
+type tokenRenewer interface {
+	renewToken()
+}
+
+// roundTrip performs the request, retrying once on 401 Unauthorized. The token of
+// an authorizer implementing tokenRenewer is renewed before the retry; other
+// authorizers, e.g. a fixed token, retry with the same token.
+func (r *curl) roundTrip(method string) (*http.Response, []byte, error) {
+	if r.fail != nil {
+		return nil, nil, r.fail
+	}
+
+	const attempts = 2
+	var resp *http.Response
+	var body []byte
+	for i := 0; i < attempts; i++ {
+		var err error
+		resp, body, err = r.once(method)
+		if err != nil {
+			return nil, nil, err
+		}
+		if resp.StatusCode != http.StatusUnauthorized {
+			break
+		}
+		if renewer, ok := r.conn.auth.(tokenRenewer); ok && i < attempts-1 {
+			r.logger().V(logDebug).Info("PrivX rejected the access token, renewing it")
+			renewer.renewToken()
+		}
+	}
+	return resp, body, nil
+}
+
+// once performs a single HTTP request.
+func (r *curl) once(method string) (*http.Response, []byte, error) {
+	ctx := r.conn.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.url, bytes.NewReader(r.payload))
+	if err != nil {
+		return nil, nil, err
+	}
+	for head := range r.header {
+		req.Header.Set(head, r.header.Get(head))
+	}
+	req.Header.Set("User-Agent", privxapi.UserAgent)
+
+	if r.conn.auth != nil {
+		token, err := r.conn.auth.AccessToken()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrAuthFailed, err)
+		}
+		req.Header.Set("Authorization", token)
+	}
+	r.addCookies(req)
+
+	resp, err := r.conn.http.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrConnect, err)
+	}
+	defer resp.Body.Close()
+
+	if r.cookieJar != nil {
+		r.cookieJar.SetCookies(req.URL, resp.Cookies())
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
+
+// addCookies adds cookies of the request or of the authorizer to req.
+func (r *curl) addCookies(req *http.Request) {
+	jar := r.cookieJar
+	if jar == nil && r.conn.auth != nil {
+		if cjp, ok := r.conn.auth.(privxapi.CookieJarProvider); ok {
+			jar = cjp.CookieJar()
+		}
+	}
+	if jar != nil {
+		r.cookieJar = jar
+		for _, cookie := range jar.Cookies(req.URL) {
+			req.AddCookie(cookie)
+		}
+		return
+	}
+	if r.conn.auth != nil {
+		if cookie := r.conn.auth.Cookie(); cookie != "" {
+			req.Header.Add("Cookie", cookie)
+		}
+	}
+}
+
+// encodeValues converts url.Values or a flat JSON-encodable struct to url.Values.
+func encodeValues(data interface{}) (url.Values, error) {
+	if values, ok := data.(url.Values); ok {
+		return values, nil
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var params map[string]interface{}
+	if err := json.Unmarshal(b, &params); err != nil {
+		return nil, err
+	}
+
+	values := url.Values{}
+	for key, param := range params {
+		switch v := param.(type) {
+		case string:
+			values.Set(key, v)
+		case float64:
+			values.Set(key, strconv.FormatFloat(v, 'g', -1, 64))
+		case bool:
+			values.Set(key, strconv.FormatBool(v))
+		default:
+			return nil, fmt.Errorf("unsupported query parameter %q of type %T", key, v)
+		}
+	}
+	return values, nil
+}
+
+// first returns the first element of in, or nil.
+func first(in []interface{}) interface{} {
+	if len(in) == 0 {
+		return nil
+	}
+	return in[0]
+}
@@ -0,0 +1,531 @@
+package privx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/vault"
+	privxapi "github.com/SSHcom/privx-sdk-go/v2/restapi"
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/apis/externalsecrets/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestNewTransport(t *testing.T) {
+	tests := []struct {
+		name            string
+		spec            *esv1.PrivxProvider
+		wantIdle        int
+		wantIdlePerHost int
+		wantIdleTimeout time.Duration
+	}{
+		{
+			name:            "defaults",
+			spec:            &esv1.PrivxProvider{},
+			wantIdle:        defaultMaxIdleConns,
+			wantIdlePerHost: defaultMaxIdleConnsPerHost,
+			wantIdleTimeout: defaultIdleConnTimeout,
+		},
+		{
+			name: "configured",
+			spec: &esv1.PrivxProvider{
+				MaxIdleConns:        ptr.To(250),
+				MaxIdleConnsPerHost: ptr.To(50),
+				IdleConnTimeout:     &metav1.Duration{Duration: 5 * time.Minute},
+			},
+			wantIdle:        250,
+			wantIdlePerHost: 50,
+			wantIdleTimeout: 5 * time.Minute,
+		},
+		{
+			name: "explicit zero",
+			spec: &esv1.PrivxProvider{
+				MaxIdleConns:    ptr.To(0),
+				IdleConnTimeout: &metav1.Duration{},
+			},
+			wantIdle:        0,
+			wantIdlePerHost: defaultMaxIdleConnsPerHost,
+			wantIdleTimeout: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := newTransport(tc.spec)
+			if transport.MaxIdleConns != tc.wantIdle {
+				t.Errorf("MaxIdleConns = %d, want %d", transport.MaxIdleConns, tc.wantIdle)
+			}
+			if transport.MaxIdleConnsPerHost != tc.wantIdlePerHost {
+				t.Errorf("MaxIdleConnsPerHost = %d, want %d", transport.MaxIdleConnsPerHost, tc.wantIdlePerHost)
+			}
+			if transport.IdleConnTimeout != tc.wantIdleTimeout {
+				t.Errorf("IdleConnTimeout = %v, want %v", transport.IdleConnTimeout, tc.wantIdleTimeout)
+			}
+		})
+	}
+}
+
+func TestConnectorVault(t *testing.T) {
+	var created vault.SecretRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.EscapedPath() == "/vault/api/v1/secrets/app":
+			_, _ = io.WriteString(w, `{"name":"app","data":{"password":"s3cr3t"}}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/vault/api/v1/secrets":
+			_ = json.NewDecoder(r.Body).Decode(&created)
+			_, _ = io.WriteString(w, `{"name":"`+created.Name+`"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"error_code":"SECRET_NOT_FOUND","error_message":"secret not found"}`)
+		}
+	}))
+	defer server.Close()
+
+	v := vault.New(newConnector(server.URL, staticAuth("Bearer token"), newTransport(&esv1.PrivxProvider{})))
+
+	secret, err := v.GetSecret("app")
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if (*secret.Data)["password"] != "s3cr3t" {
+		t.Errorf("GetSecret() data = %v", *secret.Data)
+	}
+
+	if _, err := v.CreateSecret(&vault.SecretRequest{Name: "new", Data: &map[string]interface{}{"k": "v"}}); err != nil {
+		t.Fatalf("CreateSecret() error = %v", err)
+	}
+	if created.Name != "new" || (*created.Data)["k"] != "v" {
+		t.Errorf("CreateSecret() sent %+v", created)
+	}
+
+	_, err = v.GetSecret("missing")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("GetSecret() error = %v, want StatusError 404", err)
+	}
+	if !isNotFound(err) {
+		t.Errorf("isNotFound(%v) = false", err)
+	}
+}
+
+func TestConnectorAPIBasePath(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_, _ = io.WriteString(w, `{"name":"app","data":{}}`)
+	}))
+	defer server.Close()
+
+	baseURL, err := apiBaseURL(&esv1.PrivxProvider{Host: server.URL, APIBasePath: "/privx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := vault.New(newConnector(baseURL, staticAuth("Bearer token"), newTransport(&esv1.PrivxProvider{})))
+	if _, err := v.GetSecret("app"); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if path != "/privx/vault/api/v1/secrets/app" {
+		t.Errorf("request path = %q", path)
+	}
+}
+
+func TestConnectorUnauthorizedRetry(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = io.WriteString(w, `{"name":"app","data":{}}`)
+	}))
+	defer server.Close()
+
+	v := vault.New(newConnector(server.URL, staticAuth("Bearer token"), newTransport(&esv1.PrivxProvider{})))
+	if _, err := v.GetSecret("app"); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("requests = %d, want 2", requests)
+	}
+}
+
+func TestConnectorUnauthorizedRenewsToken(t *testing.T) {
+	var tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") != "Bearer 2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = io.WriteString(w, `{"name":"app","data":{}}`)
+	}))
+	defer server.Close()
+
+	issued := 0
+	auth := newRenewableAuthorizer(func() privxapi.Authorizer {
+		issued++
+		return staticAuth(fmt.Sprintf("Bearer %d", issued))
+	})
+
+	v := vault.New(newConnector(server.URL, auth, newTransport(&esv1.PrivxProvider{})))
+	if _, err := v.GetSecret("app"); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if want := []string{"Bearer 1", "Bearer 2"}; !slices.Equal(tokens, want) {
+		t.Errorf("tokens sent = %v, want %v", tokens, want)
+	}
+}
+
+func TestConnectorContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	conn := newConnector(server.URL, staticAuth("Bearer token"), newTransport(&esv1.PrivxProvider{}))
+
+	_, err := vault.New(conn.withContext(ctx)).GetSecret("app")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetSecret() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestConnectorErrorClassification(t *testing.T) {
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer unauthorized.Close()
+	unreachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	unreachable.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		auth    privxapi.Authorizer
+		wantErr error
+	}{
+		{name: "unauthorized", url: unauthorized.URL, auth: staticAuth("Bearer token"), wantErr: ErrAuthFailed},
+		{name: "token request failed", url: unauthorized.URL, auth: failingAuth{}, wantErr: ErrAuthFailed},
+		{name: "unreachable", url: unreachable.URL, auth: staticAuth("Bearer token"), wantErr: ErrConnect},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := vault.New(newConnector(tc.url, tc.auth, newTransport(&esv1.PrivxProvider{})))
+			if _, err := v.GetSecret("app"); !errors.Is(err, tc.wantErr) {
+				t.Fatalf("GetSecret() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestStatusErrorRequestID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/vault/api/v1/secrets/traced" {
+			w.Header().Set("X-Request-Id", "req-8f3a")
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = io.WriteString(w, `{"error_code":"INTERNAL_ERROR","error_message":"internal error"}`)
+	}))
+	defer server.Close()
+
+	c := &SecretsClient{vault: vault.New(newConnector(server.URL, staticAuth("Bearer token"), newTransport(&esv1.PrivxProvider{})))}
+
+	_, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "traced"})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.RequestID != "req-8f3a" {
+		t.Fatalf("GetSecret() error = %v, want StatusError with the request ID", err)
+	}
+	if !strings.Contains(err.Error(), "req-8f3a") {
+		t.Errorf("GetSecret() error = %q, want the request ID in the message", err)
+	}
+
+	_, err = c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "untraced"})
+	if err == nil || strings.Contains(err.Error(), "request ID") {
+		t.Errorf("GetSecret() error = %v, want no request ID", err)
+	}
+}
+
+func TestStatusErrorBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/vault/api/v1/secrets/proxied" {
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = io.WriteString(w, "<html>bad gateway</html>")
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, `{"error_code":"INVALID_PARAMETER","property":"owner_id"}`)
+	}))
+	defer server.Close()
+
+	c := &SecretsClient{vault: vault.New(newConnector(server.URL, staticAuth("Bearer token"), newTransport(&esv1.PrivxProvider{})))}
+
+	_, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "app"})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Body == nil || statusErr.Body.Property != "owner_id" {
+		t.Fatalf("GetSecret() error = %v, want StatusError with the parsed body", err)
+	}
+
+	_, err = c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "proxied"})
+	if !errors.As(err, &statusErr) || statusErr.Body != nil {
+		t.Errorf("GetSecret() error = %v, want StatusError without a body", err)
+	}
+}
+
+func TestValidateNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		if strings.HasPrefix(r.URL.Path, "/vault/") {
+			_, _ = io.WriteString(w, `{"error_code":"SECRET_NOT_FOUND","error_message":"secret not found"}`)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		baseURL string
+		want    esv1.ValidationResult
+	}{
+		{name: "missing probe secret", baseURL: server.URL, want: esv1.ValidationResultReady},
+		{name: "wrong route", baseURL: server.URL + "/wrong", want: esv1.ValidationResultError},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &SecretsClient{vault: vault.New(newConnector(tc.baseURL, staticAuth("Bearer token"), newTransport(&esv1.PrivxProvider{})))}
+			if got, err := c.Validate(); got != tc.want {
+				t.Errorf("Validate() = %v, %v, want %v", got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestPing(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer token":
+			query = r.URL.RawQuery
+			_, _ = io.WriteString(w, `{"count":0,"items":[]}`)
+		case "Bearer slow":
+			<-r.Context().Done()
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		auth    string
+		timeout time.Duration
+		wantErr error
+	}{
+		{name: "reachable", auth: "Bearer token"},
+		{name: "rejected credentials", auth: "Bearer revoked", wantErr: ErrAuthFailed},
+		{name: "timeout", auth: "Bearer slow", timeout: 50 * time.Millisecond, wantErr: context.DeadlineExceeded},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := newConnector(server.URL, staticAuth(tc.auth), newTransport(&esv1.PrivxProvider{}))
+			c := &SecretsClient{
+				vault:          vault.New(conn),
+				newVault:       func(ctx context.Context) vaultClient { return vault.New(conn.withContext(ctx)) },
+				requestTimeout: tc.timeout,
+			}
+
+			err := c.Ping(context.Background())
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Ping() error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil && !errors.Is(err, ErrPingFailed) {
+				t.Errorf("Ping() error = %v, want %v", err, ErrPingFailed)
+			}
+			if tc.wantErr == nil && query != "limit=1" {
+				t.Errorf("Ping() listed with query %q, want limit=1", query)
+			}
+		})
+	}
+}
+
+// staticAuth is an Authorizer returning a fixed token.
+type staticAuth string
+
+func (a staticAuth) AccessToken() (string, error) { return string(a), nil }
+func (a staticAuth) Cookie() string               { return "" }
+
+// failingAuth is an Authorizer failing to obtain a token.
+type failingAuth struct{}
+
+func (failingAuth) AccessToken() (string, error) { return "", errors.New("invalid_client") }
+func (failingAuth) Cookie() string               { return "" }
+
+func TestCloseReleasesIdleConnections(t *testing.T) {
+	closed := make(chan struct{}, 1)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"name":"app","data":{}}`)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed <- struct{}{}
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	conn := newConnector(server.URL, staticAuth("Bearer token"), newTransport(&esv1.PrivxProvider{}))
+	c := &SecretsClient{conn: conn, vault: vault.New(conn)}
+	if _, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "app"}); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+
+	select {
+	case <-closed:
+		t.Fatal("connection closed before Close()")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle connection not closed by Close()")
+	}
+}
+
+func TestConnectorSpecialCharacterNames(t *testing.T) {
+	names := []string{"team/app/db", "my secret", "a?b#c", "100%", "x&y=z+w", "ä/ö"}
+
+	const (
+		prefix         = "/vault/api/v1/secrets"
+		metadataPrefix = "/vault/api/v1/metadata/secrets"
+	)
+	secrets := map[string]bool{}
+	for _, name := range names {
+		secrets[name] = true
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == prefix {
+			items := []vault.Secret{}
+			for name := range secrets {
+				items = append(items, vault.Secret{SecretRequest: vault.SecretRequest{Name: name}})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"count": len(items), "items": items})
+			return
+		}
+		// The path is decoded by net/http, so the name arrives intact
+		secretPrefix := prefix
+		if strings.HasPrefix(r.URL.Path, metadataPrefix+"/") {
+			secretPrefix = metadataPrefix
+		}
+		name := strings.TrimPrefix(r.URL.Path, secretPrefix+"/")
+		if !secrets[name] || strings.Count(r.URL.EscapedPath(), "/") != strings.Count(secretPrefix, "/")+1 {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"error_code":"SECRET_NOT_FOUND","error_message":"secret not found"}`)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": name, "data": map[string]string{"name": name}})
+		case http.MethodDelete:
+			delete(secrets, name)
+		}
+	}))
+	defer server.Close()
+
+	c := &SecretsClient{
+		vault: vault.New(newConnector(server.URL, staticAuth("Bearer token"), newTransport(&esv1.PrivxProvider{}))),
+	}
+	ctx := context.Background()
+
+	all, err := c.GetAllSecrets(ctx, esv1.ExternalSecretFind{
+		Name:               &esv1.FindName{RegExp: "^team/app/"},
+		ConversionStrategy: esv1.ExternalSecretConversionDefault,
+	})
+	if err != nil {
+		t.Fatalf("GetAllSecrets() error = %v", err)
+	}
+	if _, ok := all["team/app/db"]; !ok || len(all) != 1 {
+		t.Errorf("GetAllSecrets() matched %d secrets, want team/app/db", len(all))
+	}
+
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			got, err := c.GetSecret(ctx, esv1.ExternalSecretDataRemoteRef{Key: name, Property: "name"})
+			if err != nil {
+				t.Fatalf("GetSecret() error = %v", err)
+			}
+			if string(got) != name {
+				t.Errorf("GetSecret() = %q, want %q", got, name)
+			}
+
+			exists, err := c.SecretExists(ctx, v1alpha1.PushSecretRemoteRef{RemoteKey: name})
+			if err != nil || !exists {
+				t.Fatalf("SecretExists() = %v, %v, want true", exists, err)
+			}
+			if err := c.DeleteSecret(ctx, v1alpha1.PushSecretRemoteRef{RemoteKey: name}); err != nil {
+				t.Fatalf("DeleteSecret() error = %v", err)
+			}
+			if secrets[name] {
+				t.Error("secret not deleted")
+			}
+		})
+	}
+}
+
+func TestCloseDoesNotLeakGoroutines(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"name":"app","data":{}}`)
+	}))
+	defer server.Close()
+
+	// settle waits for goroutines of closed connections to exit.
+	settle := func(limit int) int {
+		n := runtime.NumGoroutine()
+		for i := 0; i < 100 && n > limit; i++ {
+			time.Sleep(10 * time.Millisecond)
+			n = runtime.NumGoroutine()
+		}
+		return n
+	}
+	before := settle(0)
+
+	for i := 0; i < 50; i++ {
+		conn := newConnector(server.URL, staticAuth("Bearer token"), newTransport(&esv1.PrivxProvider{}))
+		c := &SecretsClient{conn: conn, vault: vault.New(conn)}
+		if _, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "app"}); err != nil {
+			t.Fatalf("GetSecret() error = %v", err)
+		}
+		if err := c.Close(context.Background()); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+	}
+
+	// Allow for a few goroutines of the test server itself
+	const slack = 5
+	if after := settle(before + slack); after > before+slack {
+		t.Errorf("goroutines grew from %d to %d after closing 50 clients", before, after)
+	}
+}
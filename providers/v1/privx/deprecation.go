@@ -0,0 +1,46 @@
+/*
+Warn about PrivX store settings slated for removal.
+*/
+
+package privx
+
+import (
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// deprecation is a store setting slated for removal.
+type deprecation struct {
+	field   string
+	message string
+	applies func(*esv1.PrivxProvider) bool
+}
+
+// deprecations are checked by ValidateStore, their warnings reported in this order.
+var deprecations = []deprecation{
+	{
+		field:   "spec.provider.privx.auth.oauth",
+		message: "the four credential references are deprecated, use credentialsSecretRef instead",
+		applies: func(privx *esv1.PrivxProvider) bool {
+			return privx.Auth != nil && privx.Auth.OAuth != nil && privx.Auth.OAuth.CredentialsSecretRef == nil
+		},
+	},
+}
+
+// deprecationWarnings returns a warning for every deprecated setting of the store,
+// without duplicates and in the order of deprecations.
+func deprecationWarnings(privx *esv1.PrivxProvider) admission.Warnings {
+	var warnings admission.Warnings
+	seen := map[string]bool{}
+	for _, d := range deprecations {
+		if !d.applies(privx) {
+			continue
+		}
+		warning := d.field + ": " + d.message
+		if !seen[warning] {
+			seen[warning] = true
+			warnings = append(warnings, warning)
+		}
+	}
+	return warnings
+}
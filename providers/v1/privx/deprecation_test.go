@@ -0,0 +1,60 @@
+package privx
+
+import (
+	"reflect"
+	"testing"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestValidateStoreDeprecationWarnings(t *testing.T) {
+	tests := []struct {
+		name string
+		auth *esv1.PrivXAuth
+		want admission.Warnings
+	}{
+		{name: "no auth"},
+		{
+			name: "explicit references",
+			auth: &esv1.PrivXAuth{OAuth: explicitOAuth()},
+			want: admission.Warnings{"spec.provider.privx.auth.oauth: the four credential references are deprecated, use credentialsSecretRef instead"},
+		},
+		{
+			name: "single secret",
+			auth: &esv1.PrivXAuth{OAuth: &esv1.PrivXOAuth{CredentialsSecretRef: &esv1.PrivXCredentialsSecretRef{Name: "privx-credentials"}}},
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			warnings, err := p.ValidateStore(testStore(&esv1.PrivxProvider{Host: "https://privx.example.com", Auth: tc.auth}))
+			if err != nil {
+				t.Fatalf("ValidateStore() error = %v", err)
+			}
+			if !reflect.DeepEqual(warnings, tc.want) {
+				t.Errorf("ValidateStore() warnings = %q, want %q", warnings, tc.want)
+			}
+		})
+	}
+}
+
+func TestDeprecationWarningsOrderedAndUnique(t *testing.T) {
+	always := func(*esv1.PrivxProvider) bool { return true }
+	never := func(*esv1.PrivxProvider) bool { return false }
+
+	saved := deprecations
+	defer func() { deprecations = saved }()
+	deprecations = []deprecation{
+		{field: "spec.provider.privx.b", message: "deprecated", applies: always},
+		{field: "spec.provider.privx.a", message: "deprecated", applies: always},
+		{field: "spec.provider.privx.b", message: "deprecated", applies: always},
+		{field: "spec.provider.privx.c", message: "deprecated", applies: never},
+	}
+
+	want := admission.Warnings{"spec.provider.privx.b: deprecated", "spec.provider.privx.a: deprecated"}
+	if got := deprecationWarnings(&esv1.PrivxProvider{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("deprecationWarnings() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,220 @@
+package privx
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/filters"
+	"github.com/SSHcom/privx-sdk-go/v2/api/response"
+	"github.com/SSHcom/privx-sdk-go/v2/api/vault"
+	privxapi "github.com/SSHcom/privx-sdk-go/v2/restapi"
+)
+
+var errFakeNotFound = errors.New("error: SECRET_NOT_FOUND, message: secret not found")
+
+// fakeVault is an in-memory vaultClient used by the tests.
+type fakeVault struct {
+	secrets map[string]*vault.Secret
+
+	// users are the personal vaults by user ID, see user.
+	users map[string]*fakeVault
+
+	created []vault.SecretRequest
+	updated []vault.SecretRequest
+	deleted []string
+
+	getCalls      int
+	listCalls     int
+	metadataCalls int
+
+	// onMetadata is called by GetSecretsMetadata when set, e.g. to simulate
+	// a concurrent writer.
+	onMetadata func(secretName string)
+
+	// dropWrites makes creates and updates succeed without storing anything.
+	dropWrites bool
+
+	// listData makes GetSecrets include the data of the listed secrets.
+	listData bool
+
+	// Errors injected into the corresponding call when set.
+	getErr      error
+	metadataErr error
+	listErr     error
+	createErr   error
+	deleteErr   error
+}
+
+func newFakeVault(secrets ...vault.Secret) *fakeVault {
+	f := &fakeVault{secrets: map[string]*vault.Secret{}}
+	for i := range secrets {
+		f.secrets[secrets[i].Name] = &secrets[i]
+	}
+	return f
+}
+
+// fakeSecret builds a vault.Secret with the given name and data.
+func fakeSecret(name string, data map[string]interface{}) vault.Secret {
+	return vault.Secret{
+		SecretRequest: vault.SecretRequest{
+			Name: name,
+			Data: &data,
+		},
+	}
+}
+
+func (f *fakeVault) GetSecret(secretName string) (*vault.Secret, error) {
+	f.getCalls++
+	if f.getErr != nil {
+		return nil, f.getErr
+	}
+	s, ok := f.secrets[secretName]
+	if !ok {
+		return nil, errFakeNotFound
+	}
+	return s, nil
+}
+
+func (f *fakeVault) GetSecretsMetadata(secretName string) (*vault.Secret, error) {
+	f.metadataCalls++
+	if f.onMetadata != nil {
+		f.onMetadata(secretName)
+	}
+	if f.metadataErr != nil {
+		return nil, f.metadataErr
+	}
+	s, ok := f.secrets[secretName]
+	if !ok {
+		return nil, errFakeNotFound
+	}
+	metadata := *s
+	metadata.Data = nil
+	return &metadata, nil
+}
+
+func (f *fakeVault) GetSecrets(opts ...filters.Option) (*response.ResultSet[vault.Secret], error) {
+	f.listCalls++
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+
+	params := url.Values{}
+	for _, opt := range opts {
+		opt(&params)
+	}
+	offset, _ := strconv.Atoi(params.Get("offset"))
+	limit, err := strconv.Atoi(params.Get("limit"))
+	if err != nil {
+		limit = len(f.secrets)
+	}
+
+	names := f.sortedNames()
+
+	// Count is the total number of secrets, like the PrivX API
+	result := &response.ResultSet[vault.Secret]{Count: len(names)}
+	for i := offset; i < len(names) && i < offset+limit; i++ {
+		// List items carry no data, like the PrivX API, unless listData is set
+		item := *f.secrets[names[i]]
+		if !f.listData {
+			item.Data = nil
+		}
+		result.Items = append(result.Items, item)
+	}
+	return result, nil
+}
+
+// sortedNames returns the secret names in a stable order for paging. They are
+// not cached, as tests also write the secrets directly.
+func (f *fakeVault) sortedNames() []string {
+	names := make([]string, 0, len(f.secrets))
+	for name := range f.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (f *fakeVault) CreateSecret(secret *vault.SecretRequest) (vault.SecretCreate, error) {
+	f.created = append(f.created, *secret)
+	if f.createErr != nil {
+		return vault.SecretCreate{}, f.createErr
+	}
+	if f.dropWrites {
+		return vault.SecretCreate{Name: secret.Name}, nil
+	}
+	if _, ok := f.secrets[secret.Name]; ok {
+		// PrivX refuses to create a secret under a name in use
+		return vault.SecretCreate{}, &StatusError{
+			StatusCode: http.StatusConflict,
+			Err:        errors.New("error: SECRET_ALREADY_EXISTS, message: secret already exists"),
+			Body:       &privxapi.ErrorResponse{ErrorCode: "SECRET_ALREADY_EXISTS"},
+		}
+	}
+	f.secrets[secret.Name] = &vault.Secret{SecretRequest: *secret}
+	return vault.SecretCreate{Name: secret.Name}, nil
+}
+
+func (f *fakeVault) UpdateSecret(secretName string, secret *vault.SecretRequest) error {
+	f.updated = append(f.updated, *secret)
+	if f.dropWrites {
+		return nil
+	}
+	existing, ok := f.secrets[secretName]
+	if !ok {
+		return errFakeNotFound
+	}
+	existing.SecretRequest = *secret
+	existing.Updated = existing.Updated.Add(time.Second)
+	return nil
+}
+
+func (f *fakeVault) DeleteSecret(secretName string) error {
+	f.deleted = append(f.deleted, secretName)
+	if f.deleteErr != nil {
+		return f.deleteErr
+	}
+	if _, ok := f.secrets[secretName]; !ok {
+		return errFakeNotFound
+	}
+	delete(f.secrets, secretName)
+	return nil
+}
+
+// user returns the personal vault of userID, creating an empty one when needed.
+func (f *fakeVault) user(userID string) *fakeVault {
+	if f.users == nil {
+		f.users = map[string]*fakeVault{}
+	}
+	if f.users[userID] == nil {
+		f.users[userID] = newFakeVault()
+	}
+	return f.users[userID]
+}
+
+func (f *fakeVault) GetUserSecret(userID, secretName string) (*vault.Secret, error) {
+	return f.user(userID).GetSecret(secretName)
+}
+
+func (f *fakeVault) GetUsersSecretsMetadata(userID, secretName string) (*vault.Secret, error) {
+	return f.user(userID).GetSecretsMetadata(secretName)
+}
+
+func (f *fakeVault) GetUserSecrets(userID string, opts ...filters.Option) (*response.ResultSet[vault.Secret], error) {
+	return f.user(userID).GetSecrets(opts...)
+}
+
+func (f *fakeVault) CreateUserSecret(userID string, secret *vault.SecretRequest) (vault.SecretCreate, error) {
+	return f.user(userID).CreateSecret(secret)
+}
+
+func (f *fakeVault) UpdateUserSecret(userID, secretName string, secret *vault.SecretRequest) error {
+	return f.user(userID).UpdateSecret(secretName, secret)
+}
+
+func (f *fakeVault) DeleteUserSecret(userID, secretName string) error {
+	return f.user(userID).DeleteSecret(secretName)
+}
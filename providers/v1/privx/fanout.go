@@ -0,0 +1,201 @@
+/*
+Push the keys of a Kubernetes secret to PrivX secrets of their own.
+*/
+
+package privx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/vault"
+	corev1 "k8s.io/api/core/v1"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+var (
+	ErrFanOut          = errors.New("fan-out push incomplete")
+	ErrFanOutSecretKey = errors.New("fan-out push takes the whole secret, secretKey must be empty")
+	ErrFanOutIndexKey  = errors.New("key names the index secret of the fan-out push")
+)
+
+// fanOutKey is the data key recording the remote key of a fanned out push in each
+// of its secrets, and the names of the secrets in its index, see recordFanOut.
+// It is never returned by reads.
+const fanOutKey = "eso-fanout"
+
+// fanOutIndexSuffix follows the remote key in the name of the index secret of a
+// fanned out push, e.g. "app-.eso-fanout" for the remote key "app-".
+const fanOutIndexSuffix = ".eso-fanout"
+
+// pushFanOut writes every key of secret to the PrivX secret named by the remote key
+// followed by the key, e.g. "app-" and "password" to "app-password".
+//
+// The names do not use the name template, as deletion could not find the secrets
+// again otherwise. Every key is pushed even when others fail; ErrFanOut then lists
+// the keys pushed and the keys that failed. The secrets pushed are recorded in the
+// index secret of the remote key, see recordFanOut.
+func (c *SecretsClient) pushFanOut(ctx context.Context, secret *corev1.Secret, data esv1.PushSecretData, owner string) error {
+	if data.GetSecretKey() != "" {
+		return ErrFanOutSecretKey
+	}
+	prefix := data.GetRemoteKey()
+	if prefix == "" {
+		return ErrNoName
+	}
+
+	var pushed, failed, names []string
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(secret.Data)) {
+		if key == fanOutIndexSuffix {
+			failed = append(failed, key)
+			errs = append(errs, fmt.Errorf("key %q: %w", key, ErrFanOutIndexKey))
+			continue
+		}
+		values := map[string]interface{}{key: secret.Data[key], fanOutKey: prefix}
+		if err := c.pushData(ctx, prefix+key, owner, data.GetProperty(), key, values); err != nil {
+			failed = append(failed, key)
+			errs = append(errs, fmt.Errorf("key %q: %w", key, err))
+			continue
+		}
+		pushed = append(pushed, key)
+		names = append(names, prefix+key)
+	}
+	if len(names) > 0 {
+		if err := c.recordFanOut(ctx, prefix, names); err != nil {
+			errs = append(errs, fmt.Errorf("index %q: %w", prefix+fanOutIndexSuffix, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: pushed %q, failed %q: %w", ErrFanOut, pushed, failed, errors.Join(errs...))
+	}
+	return nil
+}
+
+// recordFanOut adds names to the index secret of the fanned out push under prefix,
+// creating it when missing. The index keeps the secrets of earlier pushes, so that
+// deleting the push also deletes the secrets of keys removed since.
+func (c *SecretsClient) recordFanOut(ctx context.Context, prefix string, names []string) error {
+	index := prefix + fanOutIndexSuffix
+	if err := c.keys.check(index); err != nil {
+		return err
+	}
+
+	c, cancel := c.withTimeout(ctx, c.requestTimeout)
+	defer cancel()
+
+	return c.modifyOrCreate(index, func(existing *vault.Secret) (*vault.SecretRequest, error) {
+		if c.managedOnly {
+			if err := checkManaged(existing); err != nil {
+				return nil, err
+			}
+		}
+		data := map[string]interface{}{}
+		if existing.Data != nil {
+			for k, v := range *existing.Data {
+				data[k] = v
+			}
+		}
+		recorded, _ := fanOutNames(data)
+		for _, name := range names {
+			if !slices.Contains(recorded, name) {
+				recorded = append(recorded, name)
+			}
+		}
+		data[fanOutKey] = recorded
+
+		update := existing.SecretRequest
+		update.Data = &data
+		return &update, nil
+	}, func() error {
+		data := map[string]interface{}{fanOutKey: names}
+		if c.managedOnly || c.requireManagedByLabel {
+			data[managedKey] = true
+		}
+		_, err := c.vault.CreateSecret(&vault.SecretRequest{
+			Name:       index,
+			ReadRoles:  packRoles(c.defaultReadRoles),
+			WriteRoles: packRoles(c.defaultWriteRoles),
+			Data:       &data,
+		})
+		return err
+	})
+}
+
+// fanOutNames returns the secret names recorded in the data of an index secret.
+// ok is false for other secrets.
+func fanOutNames(data map[string]interface{}) (names []string, ok bool) {
+	switch v := data[fanOutKey].(type) {
+	case []string:
+		return slices.Clone(v), true
+	case []interface{}:
+		for _, name := range v {
+			if s, isString := name.(string); isString {
+				names = append(names, s)
+			}
+		}
+		return names, true
+	}
+	return nil, false
+}
+
+// deleteFanOut deletes the secrets recorded in the index secret of the fanned out
+// push under the remote key of ref, and then the index itself. Without an index,
+// ref was not a fanned out push and nothing is deleted.
+// Secrets already deleted are skipped, and every secret is deleted even when others fail.
+func (c *SecretsClient) deleteFanOut(ctx context.Context, ref esv1.PushSecretRemoteRef) error {
+	prefix := ref.GetRemoteKey()
+	if prefix == "" {
+		return nil
+	}
+	index := prefix + fanOutIndexSuffix
+	if c.keys.check(index) != nil {
+		return nil
+	}
+
+	names, err := c.readFanOutIndex(ctx, index)
+	if err != nil || names == nil {
+		return err
+	}
+
+	var errs []error
+	for _, name := range names {
+		if err := c.deleteData(ctx, name, ref.GetProperty()); err != nil && !isNotFound(err) {
+			errs = append(errs, fmt.Errorf("secret %q: %w", name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrFanOut, errors.Join(errs...))
+	}
+	if err := c.deleteData(ctx, index, ""); err != nil && !isNotFound(err) {
+		return fmt.Errorf("%w: index %q: %w", ErrFanOut, index, err)
+	}
+	return nil
+}
+
+// readFanOutIndex returns the secret names recorded in the named index secret,
+// or nil when there is no such index.
+func (c *SecretsClient) readFanOutIndex(ctx context.Context, index string) ([]string, error) {
+	c, cancel := c.withTimeout(ctx, c.requestTimeout)
+	defer cancel()
+
+	secret, err := c.vault.GetSecret(index)
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if secret.Data == nil {
+		return nil, nil
+	}
+	names, ok := fanOutNames(*secret.Data)
+	if !ok {
+		return nil, nil
+	}
+	return names, nil
+}
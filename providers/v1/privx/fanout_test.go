@@ -0,0 +1,137 @@
+package privx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/apis/externalsecrets/v1alpha1"
+	testingfake "github.com/external-secrets/external-secrets/runtime/testing/fake"
+)
+
+func TestPushSecretFanOut(t *testing.T) {
+	source := testSecret(map[string][]byte{"user": []byte("admin"), "password": []byte("s3cr3t")})
+
+	tests := []struct {
+		name        string
+		secretKey   string
+		denied      []string
+		wantErr     error
+		wantCreated []string
+	}{
+		{name: "every key", wantCreated: []string{"app-password", "app-user", "app-.eso-fanout"}},
+		{
+			name:        "partial failure",
+			denied:      []string{"^app-password$"},
+			wantErr:     ErrFanOut,
+			wantCreated: []string{"app-user", "app-.eso-fanout"},
+		},
+		{name: "secret key set", secretKey: "user", wantErr: ErrFanOutSecretKey},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := newFakeVault()
+			c := newTestClient(v)
+			c.keys, _ = newKeyPolicy(nil, tc.denied)
+
+			err := c.PushSecret(context.Background(), source, testingfake.PushSecretData{
+				SecretKey: tc.secretKey,
+				RemoteKey: "app-",
+				Metadata:  pushMetadata(`{"fanOut":true}`),
+			})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("PushSecret() error = %v, want %v", err, tc.wantErr)
+			}
+
+			var created []string
+			for _, request := range v.created {
+				created = append(created, request.Name)
+			}
+			if !slices.Equal(created, tc.wantCreated) {
+				t.Errorf("created %v, want %v", created, tc.wantCreated)
+			}
+		})
+	}
+
+	t.Run("marker hidden from reads", func(t *testing.T) {
+		v := newFakeVault()
+		c := newTestClient(v)
+		if err := c.PushSecret(context.Background(), source, testingfake.PushSecretData{
+			RemoteKey: "app-",
+			Metadata:  pushMetadata(`{"fanOut":true}`),
+		}); err != nil {
+			t.Fatalf("PushSecret() error = %v", err)
+		}
+
+		got, err := c.GetSecretMap(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "app-user"})
+		if err != nil {
+			t.Fatalf("GetSecretMap() error = %v", err)
+		}
+		if want := map[string][]byte{"user": []byte("admin")}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GetSecretMap() = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestDeleteSecretFanOut(t *testing.T) {
+	v := newFakeVault(
+		fakeSecret("app-notes", map[string]interface{}{"notes": "by hand"}),
+		fakeSecret("apple", map[string]interface{}{fanOutKey: "app-"}),
+	)
+	c := newTestClient(v)
+
+	push := func(data map[string][]byte) {
+		t.Helper()
+		if err := c.PushSecret(context.Background(), testSecret(data), testingfake.PushSecretData{
+			RemoteKey: "app-",
+			Metadata:  pushMetadata(`{"fanOut":true}`),
+		}); err != nil {
+			t.Fatalf("PushSecret() error = %v", err)
+		}
+	}
+	push(map[string][]byte{"user": []byte("admin"), "password": []byte("s3cr3t")})
+	// The key removed from the source is still deleted
+	push(map[string][]byte{"user": []byte("admin")})
+
+	if err := c.DeleteSecret(context.Background(), v1alpha1.PushSecretRemoteRef{RemoteKey: "app-"}); err != nil {
+		t.Fatalf("DeleteSecret() error = %v", err)
+	}
+	if want := []string{"app-", "app-password", "app-user", "app-.eso-fanout"}; !slices.Equal(v.deleted, want) {
+		t.Errorf("deleted %v, want %v", v.deleted, want)
+	}
+	if v.listCalls != 0 {
+		t.Errorf("listCalls = %d, want 0", v.listCalls)
+	}
+
+	// Deleting again finds nothing left
+	v.deleted = nil
+	if err := c.DeleteSecret(context.Background(), v1alpha1.PushSecretRemoteRef{RemoteKey: "app-"}); err != nil {
+		t.Fatalf("second DeleteSecret() error = %v", err)
+	}
+	if want := []string{"app-"}; !slices.Equal(v.deleted, want) {
+		t.Errorf("second delete deleted %v, want %v", v.deleted, want)
+	}
+}
+
+func TestDeleteSecretNotFound(t *testing.T) {
+	v := newFakeVault(
+		fakeSecret("application", map[string]interface{}{"key": "value"}),
+		fakeSecret("app-user", map[string]interface{}{"user": "x", fanOutKey: "app"}),
+	)
+	c := newTestClient(v)
+
+	if err := c.DeleteSecret(context.Background(), v1alpha1.PushSecretRemoteRef{RemoteKey: "app"}); err != nil {
+		t.Fatalf("DeleteSecret() error = %v", err)
+	}
+	if want := []string{"app"}; !slices.Equal(v.deleted, want) {
+		t.Errorf("deleted %v, want %v", v.deleted, want)
+	}
+	// Only the missing index of a fanned out push is read
+	if v.listCalls != 0 || v.getCalls != 1 {
+		t.Errorf("listCalls = %d, getCalls = %d, want 0 and 1", v.listCalls, v.getCalls)
+	}
+}
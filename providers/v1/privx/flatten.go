@@ -0,0 +1,43 @@
+/*
+Flatten nested PrivX secret data into dotted keys.
+*/
+
+package privx
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrKeyCollision = errors.New("secret keys collide")
+
+// flattenObject returns the leaves of obj keyed by their dotted paths, e.g.
+// {"db":{"user":"x"}} becomes {"db.user":"x"}.
+//
+// Arrays are leaves, and so are empty objects, so that no key is lost. A key
+// containing a dot can produce the path of another leaf, e.g. "db.user" and
+// {"db":{"user":...}}; ErrKeyCollision names such a path.
+func flattenObject(obj map[string]interface{}) (map[string]interface{}, error) {
+	flat := map[string]interface{}{}
+	if err := flattenInto(flat, "", obj); err != nil {
+		return nil, err
+	}
+	return flat, nil
+}
+
+func flattenInto(flat map[string]interface{}, prefix string, obj map[string]interface{}) error {
+	for k, v := range obj {
+		path := prefix + k
+		if nested, ok := v.(map[string]interface{}); ok && len(nested) > 0 {
+			if err := flattenInto(flat, path+".", nested); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, exists := flat[path]; exists {
+			return fmt.Errorf("%w: %q", ErrKeyCollision, path)
+		}
+		flat[path] = v
+	}
+	return nil
+}
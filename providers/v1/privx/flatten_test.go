@@ -0,0 +1,88 @@
+package privx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+func TestGetSecretMapFlatten(t *testing.T) {
+	v := newFakeVault(
+		fakeSecret("app", map[string]interface{}{
+			"user": "admin",
+			"db": map[string]interface{}{
+				"host": "db.example.com",
+				"opts": map[string]interface{}{"ssl": true, "port": float64(5432)},
+			},
+			"hosts": []interface{}{"a", "b"},
+			"empty": map[string]interface{}{},
+		}),
+		fakeSecret("clash", map[string]interface{}{
+			"db.host": "one",
+			"db":      map[string]interface{}{"host": "two"},
+		}),
+	)
+
+	tests := []struct {
+		name     string
+		flatten  bool
+		key      string
+		property string
+		want     map[string][]byte
+		wantErr  error
+	}{
+		{
+			name: "off by default",
+			key:  "app",
+			want: map[string][]byte{
+				"user":  []byte("admin"),
+				"db":    []byte(`{"host":"db.example.com","opts":{"port":5432,"ssl":true}}`),
+				"hosts": []byte(`["a","b"]`),
+				"empty": []byte(`{}`),
+			},
+		},
+		{
+			name:    "three levels",
+			flatten: true,
+			key:     "app",
+			want: map[string][]byte{
+				"user":         []byte("admin"),
+				"db.host":      []byte("db.example.com"),
+				"db.opts.ssl":  []byte("true"),
+				"db.opts.port": []byte("5432"),
+				"hosts":        []byte(`["a","b"]`),
+				"empty":        []byte(`{}`),
+			},
+		},
+		{
+			name:     "two levels below property",
+			flatten:  true,
+			key:      "app",
+			property: "db",
+			want: map[string][]byte{
+				"host":      []byte("db.example.com"),
+				"opts.ssl":  []byte("true"),
+				"opts.port": []byte("5432"),
+			},
+		},
+		{name: "collision", flatten: true, key: "clash", wantErr: ErrKeyCollision},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestClient(v)
+			c.flattenSecretMap = tc.flatten
+
+			got, err := c.GetSecretMap(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: tc.key, Property: tc.property})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("GetSecretMap() error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr == nil && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("GetSecretMap() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -100,7 +100,7 @@ func ExchangeToken(
 
 	resp, err := httpClient.Do(r)
 	if err != nil {
-		return out, fmt.Errorf("%w: %v", ErrPrivXTokenExchangeDoRequest, err)
+		return out, fmt.Errorf("%w: %w: %v", ErrConnect, ErrPrivXTokenExchangeDoRequest, err)
 	}
 	defer resp.Body.Close()
 
@@ -115,7 +115,11 @@ func ExchangeToken(
 		if len(trimmed) > 4000 {
 			trimmed = trimmed[:4000] + "…"
 		}
-		return out, fmt.Errorf("%w: status=%d body=%s", ErrPrivXTokenExchangeBadStatus, resp.StatusCode, trimmed)
+		err := fmt.Errorf("%w: status=%d body=%s", ErrPrivXTokenExchangeBadStatus, resp.StatusCode, trimmed)
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			err = fmt.Errorf("%w: %w", ErrAuthFailed, err)
+		}
+		return out, err
 	}
 
 	if err := json.Unmarshal(respBody, &out); err != nil {
@@ -136,6 +140,7 @@ func ExchangeToken(
 func createSignedJWT(
 	ctx context.Context,
 	client kclient.Client,
+	storeKind string,
 	namespace string,
 	privateKeyRef v1.SecretKeySelector,
 	issuer string,
@@ -145,7 +150,7 @@ func createSignedJWT(
 	extraClaims map[string]any,
 ) (string, error) {
 	// Read PEM from Kubernetes Secret
-	pemStr, err := readSecretValue(ctx, client, namespace, privateKeyRef)
+	pemStr, err := readSecretValue(ctx, client, storeKind, namespace, privateKeyRef)
 	if err != nil {
 		return "", fmt.Errorf("read private key from secret: %w", err)
 	}
@@ -191,6 +196,7 @@ func createSignedJWT(
 func createSignedJWT_RS256(
 	ctx context.Context,
 	client kclient.Client,
+	storeKind string,
 	namespace string,
 	privateKeyRef v1.SecretKeySelector,
 	issuer string,
@@ -200,7 +206,7 @@ func createSignedJWT_RS256(
 	extraClaims map[string]any,
 ) (string, error) {
 	// Read PEM from Kubernetes Secret
-	pemStr, err := readSecretValue(ctx, client, namespace, privateKeyRef)
+	pemStr, err := readSecretValue(ctx, client, storeKind, namespace, privateKeyRef)
 	if err != nil {
 		return "", fmt.Errorf("read private key from secret: %w", err)
 	}
@@ -0,0 +1,76 @@
+/*
+Normalize the keys returned for PrivX secrets to valid Kubernetes Secret keys.
+*/
+
+package privx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+var (
+	ErrInvalidKeyNormalization = errors.New("invalid key normalization")
+	ErrInvalidKey              = errors.New("not a valid Kubernetes Secret key")
+)
+
+// validateKeyNormalization checks the key normalization mode of the store.
+func validateKeyNormalization(mode esv1.PrivXKeyNormalization) error {
+	switch mode {
+	case "", esv1.PrivXKeyNormalizationOff, esv1.PrivXKeyNormalizationRelaxed, esv1.PrivXKeyNormalizationStrict:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidKeyNormalization, mode)
+	}
+}
+
+// normalizeKey rewrites key to the characters allowed in Kubernetes Secret keys.
+//
+// Relaxed replaces every other character with "_", e.g. "db/host name" becomes
+// "db_host_name". Strict also lowercases the key, e.g. "DB.Host" becomes "db.host".
+func normalizeKey(mode esv1.PrivXKeyNormalization, key string) string {
+	if mode == "" || mode == esv1.PrivXKeyNormalizationOff {
+		return key
+	}
+	key = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.', r == '_':
+			return r
+		}
+		return '_'
+	}, key)
+	if mode == esv1.PrivXKeyNormalizationStrict {
+		key = strings.ToLower(key)
+	}
+	return key
+}
+
+// normalizeKeys returns data with its keys normalized, see normalizeKey.
+//
+// Distinct keys normalizing to the same key fail with ErrKeyCollision rather than
+// one value replacing the other, and keys that remain invalid, i.e. empty, "." and
+// "..", fail with ErrInvalidKey.
+func normalizeKeys(mode esv1.PrivXKeyNormalization, data map[string][]byte) (map[string][]byte, error) {
+	if mode == "" || mode == esv1.PrivXKeyNormalizationOff {
+		return data, nil
+	}
+
+	normalized := make(map[string][]byte, len(data))
+	sources := make(map[string]string, len(data))
+	for key, value := range data {
+		n := normalizeKey(mode, key)
+		switch n {
+		case "", ".", "..":
+			return nil, fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+		if other, exists := sources[n]; exists {
+			return nil, fmt.Errorf("%w: %q and %q both normalize to %q", ErrKeyCollision, other, key, n)
+		}
+		sources[n] = key
+		normalized[n] = value
+	}
+	return normalized, nil
+}
@@ -0,0 +1,106 @@
+package privx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+func TestNormalizeKey(t *testing.T) {
+	tests := []struct {
+		mode esv1.PrivXKeyNormalization
+		key  string
+		want string
+	}{
+		{mode: "", key: "db/host name", want: "db/host name"},
+		{mode: esv1.PrivXKeyNormalizationOff, key: "db/host name", want: "db/host name"},
+		{mode: esv1.PrivXKeyNormalizationRelaxed, key: "db/host name", want: "db_host_name"},
+		{mode: esv1.PrivXKeyNormalizationRelaxed, key: "DB.Host-1_a", want: "DB.Host-1_a"},
+		{mode: esv1.PrivXKeyNormalizationRelaxed, key: "pässword", want: "p_ssword"},
+		{mode: esv1.PrivXKeyNormalizationStrict, key: "DB.Host/Name", want: "db.host_name"},
+	}
+	for _, tc := range tests {
+		if got := normalizeKey(tc.mode, tc.key); got != tc.want {
+			t.Errorf("normalizeKey(%q, %q) = %q, want %q", tc.mode, tc.key, got, tc.want)
+		}
+	}
+}
+
+func TestGetSecretMapKeyNormalization(t *testing.T) {
+	v := newFakeVault(
+		fakeSecret("app", map[string]interface{}{"db/host": "db.example.com", "user": "admin"}),
+		fakeSecret("clash", map[string]interface{}{"db/host": "one", "db host": "two"}),
+		fakeSecret("dots", map[string]interface{}{"..": "x"}),
+	)
+
+	tests := []struct {
+		name    string
+		mode    esv1.PrivXKeyNormalization
+		key     string
+		want    map[string][]byte
+		wantErr error
+	}{
+		{
+			name: "off",
+			key:  "app",
+			want: map[string][]byte{"db/host": []byte("db.example.com"), "user": []byte("admin")},
+		},
+		{
+			name: "slash",
+			mode: esv1.PrivXKeyNormalizationRelaxed,
+			key:  "app",
+			want: map[string][]byte{"db_host": []byte("db.example.com"), "user": []byte("admin")},
+		},
+		{name: "collision", mode: esv1.PrivXKeyNormalizationRelaxed, key: "clash", wantErr: ErrKeyCollision},
+		{name: "invalid after normalization", mode: esv1.PrivXKeyNormalizationStrict, key: "dots", wantErr: ErrInvalidKey},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestClient(v)
+			c.keyNormalization = tc.mode
+
+			got, err := c.GetSecretMap(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: tc.key})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("GetSecretMap() error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr == nil && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("GetSecretMap() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetAllSecretsKeyNormalization(t *testing.T) {
+	v := newFakeVault(
+		fakeSecret("team/App", map[string]interface{}{"k": "v"}),
+		fakeSecret("team/app", map[string]interface{}{"k": "v"}),
+	)
+	c := newTestClient(v)
+	find := esv1.ExternalSecretFind{Name: &esv1.FindName{RegExp: "^team/"}, ConversionStrategy: esv1.ExternalSecretConversionDefault}
+
+	c.keyNormalization = esv1.PrivXKeyNormalizationRelaxed
+	got, err := c.GetAllSecrets(context.Background(), find)
+	if err != nil {
+		t.Fatalf("GetAllSecrets() error = %v", err)
+	}
+	if _, ok := got["team_App"]; !ok || len(got) != 2 {
+		t.Errorf("GetAllSecrets() keys = %v, want team_App and team_app", reflect.ValueOf(got).MapKeys())
+	}
+
+	c.keyNormalization = esv1.PrivXKeyNormalizationStrict
+	if _, err := c.GetAllSecrets(context.Background(), find); !errors.Is(err, ErrKeyCollision) {
+		t.Errorf("GetAllSecrets() error = %v, want %v", err, ErrKeyCollision)
+	}
+}
+
+func TestValidateStoreKeyNormalization(t *testing.T) {
+	p := &Provider{}
+	_, err := p.ValidateStore(testStore(&esv1.PrivxProvider{Host: "https://privx.example.com", KeyNormalization: "lower"}))
+	if !errors.Is(err, ErrInvalidKeyNormalization) {
+		t.Errorf("ValidateStore() error = %v, want %v", err, ErrInvalidKeyNormalization)
+	}
+}
@@ -0,0 +1,16 @@
+/*
+Log what the PrivX provider does, at verbosities that keep production quiet.
+*/
+
+package privx
+
+// Verbosity levels of the provider logs. Nothing is logged at the default
+// verbosity: errors are returned to the caller, which reports them.
+// The logs name secrets and keys and count them, but never include values.
+const (
+	// logDebug logs one line per operation and per request retried or failed.
+	logDebug = 1
+
+	// logTrace also logs the secrets found and the reads shared between callers.
+	logTrace = 2
+)
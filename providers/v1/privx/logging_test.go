@@ -0,0 +1,81 @@
+package privx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/apis/externalsecrets/v1alpha1"
+	testingfake "github.com/external-secrets/external-secrets/runtime/testing/fake"
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+)
+
+// logOperations runs every operation of the client, a failing push included,
+// logging to a logger of the given verbosity, and returns the lines logged.
+func logOperations(t *testing.T, verbosity int) []string {
+	t.Helper()
+	var lines []string
+	logger := funcr.New(func(prefix, args string) {
+		lines = append(lines, args)
+	}, funcr.Options{Verbosity: verbosity})
+	ctx := logr.NewContext(context.Background(), logger)
+
+	v := newFakeVault(fakeSecret("app-db", map[string]interface{}{"password": "s3cr3t"}))
+	c := newTestClient(v)
+	source := testSecret(map[string][]byte{"token": []byte("t0k3n")})
+
+	if err := c.PushSecret(ctx, source, testingfake.PushSecretData{SecretKey: "token", RemoteKey: "app-api"}); err != nil {
+		t.Fatalf("PushSecret() error = %v", err)
+	}
+	if _, err := c.GetSecret(ctx, esv1.ExternalSecretDataRemoteRef{Key: "app-db", Property: "password"}); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if _, err := c.GetSecretMap(ctx, esv1.ExternalSecretDataRemoteRef{Key: "app-db"}); err != nil {
+		t.Fatalf("GetSecretMap() error = %v", err)
+	}
+	if _, err := c.GetAllSecrets(ctx, esv1.ExternalSecretFind{ConversionStrategy: esv1.ExternalSecretConversionDefault}); err != nil {
+		t.Fatalf("GetAllSecrets() error = %v", err)
+	}
+	if err := c.DeleteSecret(ctx, v1alpha1.PushSecretRemoteRef{RemoteKey: "app-api"}); err != nil {
+		t.Fatalf("DeleteSecret() error = %v", err)
+	}
+
+	v.createErr = errors.New("error: BAD_REQUEST")
+	if err := c.PushSecret(ctx, source, testingfake.PushSecretData{SecretKey: "token", RemoteKey: "app-api"}); err == nil {
+		t.Fatal("PushSecret() succeeded, want the create error")
+	}
+	return lines
+}
+
+func TestLoggingQuietByDefault(t *testing.T) {
+	if lines := logOperations(t, 0); len(lines) != 0 {
+		t.Errorf("logged at the default verbosity:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestLoggingVerbose(t *testing.T) {
+	lines := logOperations(t, logTrace)
+	log := strings.Join(lines, "\n")
+
+	for _, want := range []string{
+		`"msg"="pushing PrivX secret" "name"="app-api" "key"="token"`,
+		`"msg"="pushing PrivX secret failed" "name"="app-api" "key"="token"`,
+		`"msg"="getting PrivX secret" "key"="app-db" "property"="password"`,
+		`"msg"="got PrivX secret map" "key"="app-db" "property"="" "keys"=1`,
+		`"msg"="found PrivX secret" "name"="app-db"`,
+		`"msg"="found PrivX secrets" "count"=2`,
+		`"msg"="deleting PrivX secret" "name"="app-api"`,
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log lacks %s:\n%s", want, log)
+		}
+	}
+	for _, value := range []string{"s3cr3t", "t0k3n"} {
+		if strings.Contains(log, value) {
+			t.Errorf("log contains the secret value %q:\n%s", value, log)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Guard PrivX secrets not pushed by external-secrets.
+*/
+
+package privx
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/vault"
+)
+
+var ErrNotManaged = errors.New("secret not managed by external-secrets")
+
+// managedKey is the data key marking secrets created by PushSecret when the store
+// sets managedOnly or requireManagedByLabel. It is never returned by reads.
+const managedKey = "eso-managed"
+
+// isManaged reports whether secret data carries the managedKey marker.
+func isManaged(data *map[string]interface{}) bool {
+	if data == nil {
+		return false
+	}
+	marked, _ := (*data)[managedKey].(bool)
+	return marked
+}
+
+// checkManaged returns ErrNotManaged for an existing secret without the marker.
+func checkManaged(existing *vault.Secret) error {
+	if !isManaged(existing.Data) {
+		return fmt.Errorf("%w: secret %q has no %q marker, refusing to overwrite it", ErrNotManaged, existing.Name, managedKey)
+	}
+	return nil
+}
+
+// checkDeletable returns ErrNotManaged when the named secret exists without the
+// managedKey marker. A missing secret is reported by the not-found error of the vault.
+func (c *SecretsClient) checkDeletable(name string) error {
+	existing, err := c.vault.GetSecret(name)
+	if err != nil {
+		return err
+	}
+	if !isManaged(existing.Data) {
+		return fmt.Errorf("%w: refusing to delete unmanaged secret %q without the %q marker", ErrNotManaged, name, managedKey)
+	}
+	return nil
+}
+
+// pushManaged writes request, creating the secret with the managedKey marker
+// when missing and updating it only when it carries the marker.
+func (c *SecretsClient) pushManaged(request *vault.SecretRequest) error {
+	(*request.Data)[managedKey] = true
+
+	return c.modifyOrCreate(request.Name, func(existing *vault.Secret) (*vault.SecretRequest, error) {
+		if err := checkManaged(existing); err != nil {
+			return nil, err
+		}
+		return request, nil
+	}, func() error {
+		_, err := c.vault.CreateSecret(request)
+		return err
+	})
+}
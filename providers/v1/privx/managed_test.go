@@ -0,0 +1,177 @@
+package privx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/vault"
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/apis/externalsecrets/v1alpha1"
+	testingfake "github.com/external-secrets/external-secrets/runtime/testing/fake"
+)
+
+func TestPushSecretManagedOnly(t *testing.T) {
+	tests := []struct {
+		name              string
+		existing          []vault.Secret
+		referenceCounting bool
+		wantErr           error
+		wantCreated       int
+		wantUpdated       int
+	}{
+		{name: "create sets marker", wantCreated: 1},
+		{
+			name:        "managed update allowed",
+			existing:    []vault.Secret{fakeSecret("app-secret", map[string]interface{}{"password": "old", managedKey: true})},
+			wantUpdated: 1,
+		},
+		{
+			name:     "unmanaged update refused",
+			existing: []vault.Secret{fakeSecret("app-secret", map[string]interface{}{"password": "by hand"})},
+			wantErr:  ErrNotManaged,
+		},
+		{name: "shared create sets marker", referenceCounting: true, wantCreated: 1},
+		{
+			name:              "shared unmanaged update refused",
+			existing:          []vault.Secret{fakeSecret("app-secret", map[string]interface{}{"password": "by hand"})},
+			referenceCounting: true,
+			wantErr:           ErrNotManaged,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := newFakeVault(tc.existing...)
+			c := newTestClient(v)
+			c.managedOnly = true
+			c.referenceCounting = tc.referenceCounting
+
+			err := c.PushSecret(context.Background(), testSecret(map[string][]byte{"password": []byte("s3cr3t")}), testingfake.PushSecretData{
+				SecretKey: "password",
+				RemoteKey: "app-secret",
+			})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("PushSecret() error = %v, want %v", err, tc.wantErr)
+			}
+			if len(v.created) != tc.wantCreated || len(v.updated) != tc.wantUpdated {
+				t.Errorf("created %d and updated %d secrets, want %d and %d", len(v.created), len(v.updated), tc.wantCreated, tc.wantUpdated)
+			}
+
+			stored := v.secrets["app-secret"].Data
+			if tc.wantErr != nil {
+				if (*stored)["password"] != "by hand" {
+					t.Errorf("unmanaged secret overwritten: %v", *stored)
+				}
+				return
+			}
+			if !isManaged(stored) {
+				t.Errorf("pushed secret %v has no marker", *stored)
+			}
+		})
+	}
+}
+
+func TestPushSecretUnguardedByDefault(t *testing.T) {
+	v := newFakeVault(fakeSecret("app-secret", map[string]interface{}{"password": "by hand"}))
+	c := newTestClient(v)
+
+	err := c.PushSecret(context.Background(), testSecret(map[string][]byte{"password": []byte("s3cr3t")}), testingfake.PushSecretData{
+		SecretKey: "password",
+		RemoteKey: "app-secret",
+	})
+	if err != nil {
+		t.Fatalf("PushSecret() error = %v", err)
+	}
+	if isManaged(v.secrets["app-secret"].Data) {
+		t.Errorf("marker written without managedOnly")
+	}
+	// The existing secret is overwritten, as PrivX refuses to create it again
+	if len(v.created) != 0 || len(v.updated) != 1 {
+		t.Errorf("created %d and updated %d secrets, want an update", len(v.created), len(v.updated))
+	}
+	if got := (*v.secrets["app-secret"].Data)["password"]; !reflect.DeepEqual(got, []byte("s3cr3t")) {
+		t.Errorf("password = %q, want the pushed value", got)
+	}
+}
+
+func TestReadsHideManagedMarker(t *testing.T) {
+	v := newFakeVault(fakeSecret("app", map[string]interface{}{"password": "s3cr3t", managedKey: true}))
+	c := newTestClient(v)
+
+	got, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "app"})
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if string(got) != `{"password":"s3cr3t"}` {
+		t.Errorf("GetSecret() = %s", got)
+	}
+}
+
+func TestDeleteSecretRequireManagedByLabel(t *testing.T) {
+	tests := []struct {
+		name              string
+		existing          []vault.Secret
+		referenceCounting bool
+		wantErr           error
+		wantDeleted       int
+	}{
+		{
+			name:        "managed deleted",
+			existing:    []vault.Secret{fakeSecret("app-secret", map[string]interface{}{"password": "s3cr3t", managedKey: true})},
+			wantDeleted: 1,
+		},
+		{
+			name:     "unmanaged refused",
+			existing: []vault.Secret{fakeSecret("app-secret", map[string]interface{}{"password": "by hand"})},
+			wantErr:  ErrNotManaged,
+		},
+		{
+			name:              "unmanaged refused with reference counting",
+			existing:          []vault.Secret{fakeSecret("app-secret", map[string]interface{}{"password": "by hand"})},
+			referenceCounting: true,
+			wantErr:           ErrNotManaged,
+		},
+		{name: "missing ignored"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := newFakeVault(tc.existing...)
+			c := newTestClient(v)
+			c.requireManagedByLabel = true
+			c.referenceCounting = tc.referenceCounting
+
+			err := c.DeleteSecret(context.Background(), v1alpha1.PushSecretRemoteRef{RemoteKey: "app-secret"})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("DeleteSecret() error = %v, want %v", err, tc.wantErr)
+			}
+			if len(v.deleted) != tc.wantDeleted {
+				t.Errorf("deleted %v, want %d deletes", v.deleted, tc.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestRequireManagedByLabelRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	v := newFakeVault()
+	c := newTestClient(v)
+	c.requireManagedByLabel = true
+
+	err := c.PushSecret(ctx, testSecret(map[string][]byte{"password": []byte("s3cr3t")}),
+		testingfake.PushSecretData{SecretKey: "password", RemoteKey: "app-secret"})
+	if err != nil {
+		t.Fatalf("PushSecret() error = %v", err)
+	}
+	if !isManaged(v.secrets["app-secret"].Data) {
+		t.Fatalf("pushed secret %v has no marker", *v.secrets["app-secret"].Data)
+	}
+	if err := c.DeleteSecret(ctx, v1alpha1.PushSecretRemoteRef{RemoteKey: "app-secret"}); err != nil {
+		t.Fatalf("DeleteSecret() error = %v", err)
+	}
+	if len(v.deleted) != 1 {
+		t.Errorf("deleted %v, want the pushed secret", v.deleted)
+	}
+}
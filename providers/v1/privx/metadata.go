@@ -0,0 +1,103 @@
+/*
+Describe PrivX secrets without their data.
+*/
+
+package privx
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/rolestore"
+	"github.com/SSHcom/privx-sdk-go/v2/api/vault"
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+// secretMetadata is returned instead of the secret data when metadata is requested,
+// either with metadataPolicy Fetch or with findMetadataOnly.
+type secretMetadata struct {
+	Name       string                 `json:"name"`
+	ReadRoles  []rolestore.RoleHandle `json:"readRoles"`
+	WriteRoles []rolestore.RoleHandle `json:"writeRoles"`
+	OwnerID    string                 `json:"ownerID,omitempty"`
+	Author     string                 `json:"author,omitempty"`
+	UpdatedBy  string                 `json:"updatedBy,omitempty"`
+	Created    time.Time              `json:"created"`
+	Updated    time.Time              `json:"updated"`
+}
+
+// newSecretMetadata returns the metadata of a PrivX secret.
+func newSecretMetadata(s *vault.Secret) secretMetadata {
+	m := secretMetadata{
+		Name:       s.Name,
+		ReadRoles:  s.ReadRoles,
+		WriteRoles: s.WriteRoles,
+		OwnerID:    s.OwnerID,
+		Author:     s.Author,
+		UpdatedBy:  s.UpdatedBy,
+		Created:    s.Created,
+		Updated:    s.Updated,
+	}
+	if m.ReadRoles == nil {
+		m.ReadRoles = []rolestore.RoleHandle{}
+	}
+	if m.WriteRoles == nil {
+		m.WriteRoles = []rolestore.RoleHandle{}
+	}
+	return m
+}
+
+// getSecretMetadata returns the metadata of a PrivX secret as JSON, without fetching its data.
+//
+// ref.Property selects a single metadata field as described in resolveProperty.
+func (c *SecretsClient) getSecretMetadata(ref esv1.ExternalSecretDataRemoteRef) ([]byte, error) {
+	secret, err := c.vault.GetSecretsMetadata(ref.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(newSecretMetadata(secret))
+	if err != nil {
+		return nil, err
+	}
+	if ref.Property == "" {
+		return b, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	v, err := resolveProperty(fields, ref.Property, c.properties)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ref.Key, err)
+	}
+	return anyToBytes(v)
+}
+
+// rolesPropertyPrefix reserves the properties selecting the roles of a secret,
+// "_roles.read" and "_roles.write", see getSecretRoles. Data keys starting with
+// the prefix cannot be selected by property.
+const rolesPropertyPrefix = "_roles."
+
+// getSecretRoles returns the read or write roles of a PrivX secret, as selected by
+// ref.Property, as a JSON array of role handles without fetching its data.
+func (c *SecretsClient) getSecretRoles(ref esv1.ExternalSecretDataRemoteRef) ([]byte, error) {
+	access := strings.TrimPrefix(ref.Property, rolesPropertyPrefix)
+	if access != "read" && access != "write" {
+		return nil, fmt.Errorf("%s: %w: %q, the roles are %sread and %swrite",
+			ref.Key, ErrPropertyNotFound, ref.Property, rolesPropertyPrefix, rolesPropertyPrefix)
+	}
+
+	secret, err := c.vault.GetSecretsMetadata(ref.Key)
+	if err != nil {
+		return nil, err
+	}
+	m := newSecretMetadata(secret)
+	if access == "read" {
+		return json.Marshal(m.ReadRoles)
+	}
+	return json.Marshal(m.WriteRoles)
+}
@@ -0,0 +1,157 @@
+package privx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/rolestore"
+	"github.com/SSHcom/privx-sdk-go/v2/api/vault"
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"k8s.io/utils/ptr"
+)
+
+func metadataVault() *fakeVault {
+	updated := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	secret := fakeSecret("app-db", map[string]interface{}{"password": "s3cr3t"})
+	secret.ReadRoles = []rolestore.RoleHandle{{ID: "r1", Name: "readers"}}
+	secret.OwnerID = "owner-1"
+	secret.Author = "admin"
+	secret.Updated = updated
+	return newFakeVault(secret, fakeSecret("app-cache", map[string]interface{}{"token": "t0k3n"}))
+}
+
+func TestGetSecretMetadataPolicyFetch(t *testing.T) {
+	tests := []struct {
+		name     string
+		property string
+		want     string
+		wantErr  error
+	}{
+		{name: "owner", property: "ownerID", want: "owner-1"},
+		{name: "role name", property: "readRoles.0.name", want: "readers"},
+		{name: "updated", property: "updated", want: "2026-01-02T03:04:05Z"},
+		{name: "missing field", property: "tags", wantErr: ErrPropertyNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := metadataVault()
+			c := newTestClient(v)
+			got, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{
+				Key:            "app-db",
+				Property:       tc.property,
+				MetadataPolicy: esv1.ExternalSecretMetadataPolicyFetch,
+			})
+			if tc.wantErr != nil {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr.Error()) {
+					t.Fatalf("GetSecret() error = %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetSecret() error = %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("GetSecret() = %q, want %q", got, tc.want)
+			}
+			if v.getCalls != 0 {
+				t.Errorf("fetched secret data %d times, want 0", v.getCalls)
+			}
+		})
+	}
+}
+
+func TestGetAllSecretsMetadataOnly(t *testing.T) {
+	find := esv1.ExternalSecretFind{
+		Name:               &esv1.FindName{RegExp: "^app-"},
+		ConversionStrategy: esv1.ExternalSecretConversionDefault,
+	}
+
+	v := metadataVault()
+	c := newTestClient(v)
+	values, err := c.GetAllSecrets(context.Background(), find)
+	if err != nil {
+		t.Fatalf("GetAllSecrets() error = %v", err)
+	}
+
+	mv := metadataVault()
+	c = newTestClient(mv)
+	c.findMetadataOnly = true
+	metadata, err := c.GetAllSecrets(context.Background(), find)
+	if err != nil {
+		t.Fatalf("GetAllSecrets() metadata only error = %v", err)
+	}
+
+	if len(metadata) != len(values) {
+		t.Fatalf("metadata find returned %d secrets, value find %d", len(metadata), len(values))
+	}
+	for name := range values {
+		var got secretMetadata
+		if err := json.Unmarshal(metadata[name], &got); err != nil {
+			t.Fatalf("secret %s: %v", name, err)
+		}
+		if got.Name != name {
+			t.Errorf("secret %s metadata name = %q", name, got.Name)
+		}
+		if strings.Contains(string(metadata[name]), "s3cr3t") || strings.Contains(string(metadata[name]), "t0k3n") {
+			t.Errorf("secret %s metadata contains data: %s", name, metadata[name])
+		}
+	}
+	if want := string(mustMetadata(t, mv.secrets["app-db"])); string(metadata["app-db"]) != want {
+		t.Errorf("metadata[app-db] = %s, want %s", metadata["app-db"], want)
+	}
+
+	if v.getCalls != len(values) {
+		t.Errorf("value find fetched %d secrets, want %d", v.getCalls, len(values))
+	}
+	if mv.getCalls != 0 {
+		t.Errorf("metadata find fetched %d secrets, want 0", mv.getCalls)
+	}
+}
+
+func mustMetadata(t *testing.T, s *vault.Secret) []byte {
+	t.Helper()
+	b, err := json.Marshal(newSecretMetadata(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestGetSecretRoles(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		property string
+		want     string
+		wantErr  error
+	}{
+		{name: "read", key: "app-db", property: "_roles.read", want: `[{"id":"r1","name":"readers"}]`},
+		{name: "write", key: "app-db", property: "_roles.write", want: `[]`},
+		{name: "unknown access", key: "app-db", property: "_roles.admin", wantErr: ErrPropertyNotFound},
+		{name: "data key with the prefix", key: "shadowed", property: "_roles.read", want: `[]`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := metadataVault()
+			v.secrets["shadowed"] = ptr.To(fakeSecret("shadowed", map[string]interface{}{"_roles.read": "data"}))
+			c := newTestClient(v)
+
+			got, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: tc.key, Property: tc.property})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("GetSecret() error = %v, want %v", err, tc.wantErr)
+			}
+			if string(got) != tc.want {
+				t.Errorf("GetSecret() = %s, want %s", got, tc.want)
+			}
+			if v.getCalls != 0 {
+				t.Errorf("fetched secret data %d times, want 0", v.getCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+/*
+Derive PrivX secret names from the store name template.
+*/
+
+package privx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+var (
+	ErrInvalidNameTemplate = errors.New("invalid name template")
+)
+
+// Variables available to the name template.
+const (
+	nameVarNamespace  = "Namespace"
+	nameVarSecretName = "SecretName"
+	nameVarSecretKey  = "SecretKey"
+	nameVarRemoteKey  = "RemoteKey"
+	nameVarProperty   = "Property"
+)
+
+// parseNameTemplate parses the store name template.
+//
+// Referring to a variable that is not available is an error when the template is executed.
+func parseNameTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("nameTemplate").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidNameTemplate, err)
+	}
+	return tmpl, nil
+}
+
+// secretName returns the PrivX secret name for the template variables in vars.
+//
+// Without a name template the name is fallback.
+func (c *SecretsClient) secretName(vars map[string]string, fallback string) (string, error) {
+	if c.nameTemplate == nil {
+		return fallback, nil
+	}
+
+	var b strings.Builder
+	if err := c.nameTemplate.Execute(&b, vars); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidNameTemplate, err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,115 @@
+package privx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/apis/externalsecrets/v1alpha1"
+	testingfake "github.com/external-secrets/external-secrets/runtime/testing/fake"
+)
+
+func TestPushSecretNameTemplate(t *testing.T) {
+	tests := []struct {
+		name      string
+		template  string
+		remoteKey string
+		want      string
+		wantErr   error
+	}{
+		{name: "no template uses remote key", remoteKey: "remote", want: "remote"},
+		{name: "no template falls back to secret name", want: "source"},
+		{
+			name:      "naming policy",
+			template:  "{{ .Namespace }}-{{ .SecretName }}-{{ .SecretKey }}",
+			remoteKey: "ignored",
+			want:      "default-source-password",
+		},
+		{
+			name:      "remote key prefix",
+			template:  "team-a/{{ .RemoteKey }}",
+			remoteKey: "db",
+			want:      "team-a/db",
+		},
+		{name: "unknown variable", template: "{{ .Nope }}", wantErr: ErrInvalidNameTemplate},
+		{name: "empty result", template: "{{ .RemoteKey }}", wantErr: ErrNoName},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := newFakeVault()
+			c := newTestClient(v)
+			if tc.template != "" {
+				tmpl, err := parseNameTemplate(tc.template)
+				if err != nil {
+					t.Fatal(err)
+				}
+				c.nameTemplate = tmpl
+			}
+
+			err := c.PushSecret(context.Background(), testSecret(map[string][]byte{"password": []byte("s3cr3t")}), testingfake.PushSecretData{
+				SecretKey: "password",
+				RemoteKey: tc.remoteKey,
+			})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("PushSecret() error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			if _, ok := v.secrets[tc.want]; !ok {
+				t.Errorf("secret %q not created, created %+v", tc.want, v.created)
+			}
+		})
+	}
+}
+
+func TestDeleteSecretNameTemplate(t *testing.T) {
+	v := newFakeVault(fakeSecret("default-db", map[string]interface{}{"password": "s3cr3t"}))
+	c := newTestClient(v)
+	tmpl, err := parseNameTemplate("{{ .Namespace }}-{{ .RemoteKey }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.nameTemplate = tmpl
+	ref := v1alpha1.PushSecretRemoteRef{RemoteKey: "db"}
+
+	exists, err := c.SecretExists(context.Background(), ref)
+	if err != nil || !exists {
+		t.Fatalf("SecretExists() = %v, %v, want true", exists, err)
+	}
+	if err := c.DeleteSecret(context.Background(), ref); err != nil {
+		t.Fatalf("DeleteSecret() error = %v", err)
+	}
+	if _, ok := v.secrets["default-db"]; ok {
+		t.Error("secret default-db was not deleted")
+	}
+
+	// The source secret is not known on deletion
+	tmpl, err = parseNameTemplate("{{ .SecretName }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.nameTemplate = tmpl
+	if err := c.DeleteSecret(context.Background(), ref); !errors.Is(err, ErrInvalidNameTemplate) {
+		t.Errorf("DeleteSecret() error = %v, want ErrInvalidNameTemplate", err)
+	}
+}
+
+func TestValidateStoreNameTemplate(t *testing.T) {
+	p := &Provider{}
+	for template, wantErr := range map[string]error{
+		"":                                  nil,
+		"{{ .Namespace }}-{{ .RemoteKey }}": nil,
+		"{{ .Namespace ":                    ErrInvalidNameTemplate,
+	} {
+		_, err := p.ValidateStore(testStore(&esv1.PrivxProvider{
+			Host:         "https://privx.example.com",
+			NameTemplate: template,
+		}))
+		if !errors.Is(err, wantErr) {
+			t.Errorf("ValidateStore(nameTemplate %q) error = %v, want %v", template, err, wantErr)
+		}
+	}
+}
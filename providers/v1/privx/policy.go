@@ -0,0 +1,84 @@
+/*
+Restrict the PrivX secrets a store may access.
+*/
+
+package privx
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+var (
+	ErrKeyNotPermitted = errors.New("key not permitted by store policy")
+)
+
+// keyPolicy restricts the PrivX secret names a store may read and write.
+//
+// A name is permitted when no denied pattern matches any part of it and, if any
+// allowed patterns are given, at least one of them matches the whole name, so that
+// allowing "app" does not allow "my-app-admin-token". Denial takes precedence.
+type keyPolicy struct {
+	allowed []*regexp.Regexp
+	denied  []*regexp.Regexp
+}
+
+// newKeyPolicy compiles the allowed and denied regular expressions.
+func newKeyPolicy(allowed, denied []string) (*keyPolicy, error) {
+	allowedRegexps, err := compilePatterns("allowedKeys", allowed, true)
+	if err != nil {
+		return nil, err
+	}
+	deniedRegexps, err := compilePatterns("deniedKeys", denied, false)
+	if err != nil {
+		return nil, err
+	}
+	return &keyPolicy{allowed: allowedRegexps, denied: deniedRegexps}, nil
+}
+
+// compilePatterns compiles the regular expressions of field, anchored to match
+// whole names when anchored is set.
+func compilePatterns(field string, patterns []string, anchored bool) ([]*regexp.Regexp, error) {
+	regexps := make([]*regexp.Regexp, 0, len(patterns))
+	for i, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("%s[%d]: invalid regex %q: %w", field, i, pattern, err)
+		}
+		if anchored {
+			pattern = "^(?:" + pattern + ")$"
+		}
+		regexps = append(regexps, regexp.MustCompile(pattern))
+	}
+	return regexps, nil
+}
+
+// permits reports whether the policy permits the secret name.
+// A nil policy permits every name.
+func (p *keyPolicy) permits(name string) bool {
+	if p == nil {
+		return true
+	}
+	for _, re := range p.denied {
+		if re.MatchString(name) {
+			return false
+		}
+	}
+	if len(p.allowed) == 0 {
+		return true
+	}
+	for _, re := range p.allowed {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns ErrKeyNotPermitted when the policy does not permit the secret name.
+func (p *keyPolicy) check(name string) error {
+	if !p.permits(name) {
+		return fmt.Errorf("%w: %s", ErrKeyNotPermitted, name)
+	}
+	return nil
+}
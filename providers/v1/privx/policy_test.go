@@ -0,0 +1,90 @@
+package privx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/apis/externalsecrets/v1alpha1"
+	testingfake "github.com/external-secrets/external-secrets/runtime/testing/fake"
+)
+
+func TestKeyPolicyPermits(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		denied  []string
+		key     string
+		want    bool
+	}{
+		{name: "no policy", key: "anything", want: true},
+		{name: "allowed", allowed: []string{"app-.*"}, key: "app-db", want: true},
+		{name: "not allowed", allowed: []string{"app-.*"}, key: "infra-db", want: false},
+		{name: "allowed matches whole names", allowed: []string{"app"}, key: "my-app-admin-token", want: false},
+		{name: "allowed alternatives match whole names", allowed: []string{"app|db"}, key: "app-db", want: false},
+		{name: "denied", denied: []string{"-admin$"}, key: "app-admin", want: false},
+		{name: "denied matches part of a name", denied: []string{"admin"}, key: "app-admin-token", want: false},
+		{name: "deny takes precedence", allowed: []string{"app-.*"}, denied: []string{"-admin$"}, key: "app-admin", want: false},
+		{name: "any allowed pattern", allowed: []string{"app-.*", "shared-.*"}, key: "shared-ca", want: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := newKeyPolicy(tc.allowed, tc.denied)
+			if err != nil {
+				t.Fatalf("newKeyPolicy() error = %v", err)
+			}
+			if got := policy.permits(tc.key); got != tc.want {
+				t.Errorf("permits(%q) = %v, want %v", tc.key, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestKeyPolicyEnforced(t *testing.T) {
+	ctx := context.Background()
+	v := newFakeVault(
+		fakeSecret("app-db", map[string]interface{}{"password": "s3cr3t"}),
+		fakeSecret("infra-db", map[string]interface{}{"password": "s3cr3t"}),
+	)
+	c := newTestClient(v)
+	c.keys, _ = newKeyPolicy([]string{"app-.*"}, nil)
+
+	denied := map[string]error{}
+	_, denied["GetSecret"] = c.GetSecret(ctx, esv1.ExternalSecretDataRemoteRef{Key: "infra-db"})
+	_, denied["GetSecretMap"] = c.GetSecretMap(ctx, esv1.ExternalSecretDataRemoteRef{Key: "infra-db"})
+	denied["PushSecret"] = c.PushSecret(ctx, testSecret(map[string][]byte{"k": []byte("v")}), testingfake.PushSecretData{
+		SecretKey: "k",
+		RemoteKey: "infra-new",
+	})
+	denied["DeleteSecret"] = c.DeleteSecret(ctx, v1alpha1.PushSecretRemoteRef{RemoteKey: "infra-db"})
+	for op, err := range denied {
+		if !errors.Is(err, ErrKeyNotPermitted) {
+			t.Errorf("%s() error = %v, want %v", op, err, ErrKeyNotPermitted)
+		}
+	}
+	if v.getCalls != 0 || len(v.created) != 0 || len(v.deleted) != 0 {
+		t.Errorf("denied operations reached PrivX")
+	}
+
+	got, err := c.GetAllSecrets(ctx, esv1.ExternalSecretFind{ConversionStrategy: esv1.ExternalSecretConversionDefault})
+	if err != nil {
+		t.Fatalf("GetAllSecrets() error = %v", err)
+	}
+	if _, ok := got["infra-db"]; ok || len(got) != 1 {
+		t.Errorf("GetAllSecrets() returned %d secrets, want only app-db", len(got))
+	}
+}
+
+func TestValidateStoreKeyPolicy(t *testing.T) {
+	p := &Provider{}
+	_, err := p.ValidateStore(testStore(&esv1.PrivxProvider{
+		Host:       "https://privx.example.com",
+		DeniedKeys: []string{"^ok$", "(unclosed"},
+	}))
+	if err == nil || !strings.Contains(err.Error(), "spec.provider.privx.deniedKeys[1]") {
+		t.Fatalf("ValidateStore() error = %v, want invalid deniedKeys[1]", err)
+	}
+}
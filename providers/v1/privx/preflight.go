@@ -0,0 +1,94 @@
+/*
+Check the role configuration of a PrivX store when a client is created.
+*/
+
+package privx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/rolestore"
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+var (
+	ErrNoWriteRoles    = errors.New("no write roles")
+	ErrUnresolvedRoles = errors.New("roles not found in PrivX")
+)
+
+// roleLookup is the subset of the PrivX role store API used by preflightRoles.
+type roleLookup interface {
+	GetRole(roleID string) (*rolestore.Role, error)
+}
+
+// preflight checks the role configuration of the store once, when the client is
+// created, so that a store unable to push fails immediately rather than on the
+// first PushSecret. It does nothing unless the store enables preflightRoles, or
+// for personal vaults, which are not shared by role.
+func preflight(ctx context.Context, conn *connector, privxSpec *esv1.PrivxProvider, timeout time.Duration) error {
+	if !privxSpec.PreflightRoles || privxSpec.VaultScope == esv1.PrivXVaultScopePersonal {
+		return nil
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return preflightRoles(rolestore.New(conn.withContext(ctx)), privxSpec.DefaultReadRoles, privxSpec.DefaultWriteRoles)
+}
+
+// preflightRoles checks that the default read and write roles exist in PrivX and
+// that at least one write role remains, since PrivX refuses secrets nobody can write.
+//
+// ErrNoWriteRoles is returned when no write role is configured or none of them
+// exists, ErrUnresolvedRoles when some other configured role does not exist.
+func preflightRoles(roles roleLookup, readRoles, writeRoles []string) error {
+	unresolvedRead, err := unresolvedRoles(roles, readRoles)
+	if err != nil {
+		return err
+	}
+	unresolvedWrite, err := unresolvedRoles(roles, writeRoles)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(writeRoles) == 0:
+		return fmt.Errorf("spec.provider.privx.defaultWriteRoles: %w: the store cannot push secrets", ErrNoWriteRoles)
+	case len(unresolvedWrite) == len(writeRoles):
+		return fmt.Errorf("spec.provider.privx.defaultWriteRoles: %w: none of %v exists in PrivX", ErrNoWriteRoles, writeRoles)
+	case len(unresolvedWrite) > 0:
+		return fmt.Errorf("spec.provider.privx.defaultWriteRoles: %w: %v", ErrUnresolvedRoles, unresolvedWrite)
+	case len(unresolvedRead) > 0:
+		return fmt.Errorf("spec.provider.privx.defaultReadRoles: %w: %v", ErrUnresolvedRoles, unresolvedRead)
+	}
+	return nil
+}
+
+// unresolvedRoles returns the role IDs that do not exist in PrivX.
+func unresolvedRoles(roles roleLookup, roleIDs []string) ([]string, error) {
+	var unresolved []string
+	for _, id := range roleIDs {
+		if _, err := roles.GetRole(id); err != nil {
+			if !isRoleNotFound(err) {
+				return nil, fmt.Errorf("resolving role %q: %w", id, err)
+			}
+			unresolved = append(unresolved, id)
+		}
+	}
+	return unresolved, nil
+}
+
+// isRoleNotFound returns whether PrivX reported that a role does not exist: a 404
+// response with a PrivX error body. A 404 without one, e.g. from a proxy or a wrong
+// apiBasePath, says nothing about the role.
+func isRoleNotFound(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound &&
+		statusErr.Body != nil && strings.HasSuffix(statusErr.Body.ErrorCode, "NOT_FOUND")
+}
@@ -0,0 +1,126 @@
+package privx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/rolestore"
+	privxapi "github.com/SSHcom/privx-sdk-go/v2/restapi"
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	readRoleID  = "11111111-1111-1111-1111-111111111111"
+	writeRoleID = "22222222-2222-2222-2222-222222222222"
+	goneRoleID  = "33333333-3333-3333-3333-333333333333"
+)
+
+// fakeRoles is a PrivX role store holding the listed roles.
+type fakeRoles struct {
+	ids []string
+	err error
+}
+
+func (f *fakeRoles) GetRole(roleID string) (*rolestore.Role, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	for _, id := range f.ids {
+		if id == roleID {
+			return &rolestore.Role{ID: id}, nil
+		}
+	}
+	return nil, &StatusError{
+		StatusCode: http.StatusNotFound,
+		Err:        errors.New("error: ROLE_NOT_FOUND"),
+		Body:       &privxapi.ErrorResponse{ErrorCode: "ROLE_NOT_FOUND"},
+	}
+}
+
+func TestPreflightRoles(t *testing.T) {
+	unavailable := &StatusError{StatusCode: http.StatusServiceUnavailable, Err: errors.New("unavailable")}
+	wrongRoute := &StatusError{StatusCode: http.StatusNotFound, Err: errors.New("HTTP error: 404 Not Found")}
+	existing := &fakeRoles{ids: []string{readRoleID, writeRoleID}}
+
+	tests := []struct {
+		name       string
+		roles      roleLookup
+		readRoles  []string
+		writeRoles []string
+		wantErr    error
+	}{
+		{name: "resolved", roles: existing, readRoles: []string{readRoleID}, writeRoles: []string{writeRoleID}},
+		{name: "no read roles", roles: existing, writeRoles: []string{writeRoleID}},
+		{name: "no write roles", roles: existing, readRoles: []string{readRoleID}, wantErr: ErrNoWriteRoles},
+		{name: "empty after resolution", roles: existing, writeRoles: []string{goneRoleID}, wantErr: ErrNoWriteRoles},
+		{name: "some write roles missing", roles: existing, writeRoles: []string{writeRoleID, goneRoleID}, wantErr: ErrUnresolvedRoles},
+		{name: "read role missing", roles: existing, readRoles: []string{goneRoleID}, writeRoles: []string{writeRoleID}, wantErr: ErrUnresolvedRoles},
+		{name: "lookup failure", roles: &fakeRoles{err: unavailable}, writeRoles: []string{writeRoleID}, wantErr: unavailable},
+		{name: "404 without PrivX error", roles: &fakeRoles{err: wrongRoute}, writeRoles: []string{writeRoleID}, wantErr: wrongRoute},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := preflightRoles(tc.roles, tc.readRoles, tc.writeRoles)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("preflightRoles() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewClientPreflightRoles(t *testing.T) {
+	var rolePaths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/oauth/token") {
+			_, _ = io.WriteString(w, `{"access_token":"token","expires_in":300}`)
+			return
+		}
+		rolePaths = append(rolePaths, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"error_code":"NOT_FOUND"}`)
+	}))
+	defer server.Close()
+
+	kube := clientfake.NewClientBuilder().WithObjects(
+		kubeSecret("privx-credentials", map[string]string{
+			"clientID":        "oauth-id",
+			"clientSecret":    "oauth-secret",
+			"apiClientID":     "api-id",
+			"apiClientSecret": "api-secret",
+		}),
+	).Build()
+	spec := &esv1.PrivxProvider{
+		Host: server.URL,
+		Auth: &esv1.PrivXAuth{OAuth: &esv1.PrivXOAuth{
+			CredentialsSecretRef: &esv1.PrivXCredentialsSecretRef{Name: "privx-credentials"},
+		}},
+		DefaultWriteRoles: []string{goneRoleID},
+	}
+	p := &Provider{}
+
+	client, err := p.NewClient(context.Background(), testStore(spec), kube, "default")
+	if err != nil {
+		t.Fatalf("NewClient() without preflightRoles error = %v", err)
+	}
+	_ = client.Close(context.Background())
+	if len(rolePaths) != 0 {
+		t.Errorf("NewClient() without preflightRoles looked up roles %v", rolePaths)
+	}
+
+	spec.PreflightRoles = true
+	_, err = p.NewClient(context.Background(), testStore(spec), kube, "default")
+	if !errors.Is(err, ErrNoWriteRoles) {
+		t.Fatalf("NewClient() error = %v, want %v", err, ErrNoWriteRoles)
+	}
+	if len(rolePaths) != 1 || !strings.HasSuffix(rolePaths[0], "/roles/"+goneRoleID) {
+		t.Errorf("role lookups = %v, want the write role", rolePaths)
+	}
+}
@@ -0,0 +1,278 @@
+/*
+Resolve properties of PrivX secret data.
+*/
+
+package privx
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/jmespath/go-jmespath"
+)
+
+var (
+	ErrIndexOutOfRange   = errors.New("array index out of range")
+	ErrNotArray          = errors.New("not an array")
+	ErrAmbiguousProperty = errors.New("property matches several keys")
+	ErrInvalidJMESPath   = errors.New("invalid JMESPath expression")
+	ErrObjectProperty    = errors.New("property selects an object")
+	ErrPropertyRequired  = errors.New("property required")
+	ErrInvalidMatch      = errors.New("invalid property match")
+)
+
+// jmesPathPrefix marks a property as a JMESPath expression, see searchJMESPath.
+const jmesPathPrefix = "jmespath:"
+
+// propertyOptions configure how properties are resolved.
+type propertyOptions struct {
+	// caseInsensitive matches object keys regardless of case, see lookup.
+	caseInsensitive bool
+
+	// pathFirst tries a dot-separated path before an exact top-level key,
+	// see resolveProperty.
+	pathFirst bool
+}
+
+// newPropertyOptions returns the property options of the store.
+func newPropertyOptions(privxSpec *esv1.PrivxProvider) propertyOptions {
+	return propertyOptions{
+		caseInsensitive: privxSpec.CaseInsensitiveProperty,
+		pathFirst:       privxSpec.PropertyMatch == esv1.PrivXPropertyMatchPathFirst,
+	}
+}
+
+// validatePropertyMatch checks the property match strategy of the store.
+func validatePropertyMatch(match esv1.PrivXPropertyMatch) error {
+	switch match {
+	case "", esv1.PrivXPropertyMatchLiteralFirst, esv1.PrivXPropertyMatchPathFirst:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidMatch, match)
+	}
+}
+
+// lookup returns the value of key in an object.
+//
+// With caseInsensitive, key matches the object keys regardless of case and
+// ErrAmbiguousProperty is returned when it matches more than one of them.
+func (o propertyOptions) lookup(node map[string]interface{}, key string) (any, bool, error) {
+	if !o.caseInsensitive {
+		v, ok := node[key]
+		return v, ok, nil
+	}
+
+	var matches []string
+	for k := range node {
+		if strings.EqualFold(k, key) {
+			matches = append(matches, k)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, false, nil
+	case 1:
+		return node[matches[0]], true, nil
+	default:
+		sort.Strings(matches)
+		return nil, false, fmt.Errorf("%w: %q matches %s", ErrAmbiguousProperty, key, strings.Join(matches, ", "))
+	}
+}
+
+// resolveProperty returns the value of property within secret data.
+//
+// The property is resolved in the following order:
+//
+//   - an exact top-level key, e.g. "db.host" when such a key exists
+//   - a JSON Pointer (RFC 6901) when the property starts with "/", e.g. "/db/host"
+//   - a dot-separated path through nested objects, e.g. "db.host", where array
+//     elements are selected by bracket indices, e.g. "db.hosts[0].name"
+//
+// With pathFirst, a dot-separated path that resolves takes precedence over an exact
+// top-level key instead, e.g. "db.host" selects the host of a "db" object even when
+// a "db.host" key exists. JSON Pointers are never ambiguous.
+//
+// An empty property returns the data itself.
+// ErrPropertyNotFound is returned when the property does not resolve to a non-null value,
+// together with ErrIndexOutOfRange or ErrNotArray for a bracket index that cannot apply.
+// Object keys are matched as configured by opts.
+func resolveProperty(data map[string]interface{}, property string, opts propertyOptions) (any, error) {
+	if property == "" {
+		return data, nil
+	}
+
+	if opts.pathFirst && !strings.HasPrefix(property, "/") {
+		v, err := resolvePath(data, parsePath(property), opts)
+		if errors.Is(err, ErrAmbiguousProperty) {
+			return nil, err
+		}
+		if err == nil && v != nil {
+			return v, nil
+		}
+	}
+
+	v, ok, err := opts.lookup(data, property)
+	if err != nil {
+		return nil, err
+	}
+	if ok && v != nil {
+		return v, nil
+	}
+
+	if strings.HasPrefix(property, "/") {
+		v, err = resolvePointer(data, property, opts)
+	} else {
+		v, err = resolvePath(data, parsePath(property), opts)
+	}
+	if err == nil && v == nil {
+		err = ErrPropertyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, property)
+	}
+	return v, nil
+}
+
+// resolveProperties returns the values of a comma-separated list of properties,
+// e.g. "user,password", as an object keyed by property. Each property is resolved
+// as described in resolveProperty.
+//
+// A property without a comma, or naming an exact top-level key, is resolved as a
+// single property. ErrPropertyNotFound names the first property that does not resolve.
+func resolveProperties(data map[string]interface{}, property string, opts propertyOptions) (any, error) {
+	if !isPropertyList(data, property, opts) {
+		return resolveProperty(data, property, opts)
+	}
+
+	selected := map[string]interface{}{}
+	for _, p := range strings.Split(property, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			return nil, fmt.Errorf("%w: empty property in %q", ErrPropertyNotFound, property)
+		}
+		v, err := resolveProperty(data, p, opts)
+		if err != nil {
+			return nil, err
+		}
+		selected[p] = v
+	}
+	return selected, nil
+}
+
+// isPropertyList reports whether property is a list of properties, see resolveProperties.
+func isPropertyList(data map[string]interface{}, property string, opts propertyOptions) bool {
+	if _, ok, _ := opts.lookup(data, property); ok {
+		return false
+	}
+	return strings.Contains(property, ",")
+}
+
+// searchJMESPath evaluates a JMESPath expression, e.g. "servers[?env=='prod'].host",
+// against secret data. ErrPropertyNotFound is returned when the result is null.
+func searchJMESPath(data map[string]interface{}, expression string) (any, error) {
+	compiled, err := jmespath.Compile(expression)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrInvalidJMESPath, expression, err)
+	}
+	v, err := compiled.Search(data)
+	if err != nil {
+		return nil, fmt.Errorf("JMESPath %q: %w", expression, err)
+	}
+	if v == nil {
+		return nil, fmt.Errorf("%w: JMESPath %q", ErrPropertyNotFound, expression)
+	}
+	return v, nil
+}
+
+// resolvePointer resolves a JSON Pointer such as "/db/hosts/0".
+func resolvePointer(data map[string]interface{}, pointer string, opts propertyOptions) (any, error) {
+	tokens := strings.Split(pointer[1:], "/")
+	steps := make([]pathStep, len(tokens))
+	for i, token := range tokens {
+		token = strings.ReplaceAll(token, "~1", "/")
+		steps[i] = pathStep{key: strings.ReplaceAll(token, "~0", "~")}
+	}
+	return resolvePath(data, steps, opts)
+}
+
+// pathStep is a step of a property path: an object key, or an array index given
+// in brackets. A numeric key also selects an array element, as in "hosts.0".
+type pathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parsePath splits a dot-separated path such as "db.hosts[0].name" into steps.
+//
+// A segment whose brackets do not hold integer indices, e.g. "a[b]", is a plain key.
+func parsePath(property string) []pathStep {
+	var steps []pathStep
+	for _, segment := range strings.Split(property, ".") {
+		steps = append(steps, parseSegment(segment)...)
+	}
+	return steps
+}
+
+func parseSegment(segment string) []pathStep {
+	open := strings.IndexByte(segment, '[')
+	if open < 0 || !strings.HasSuffix(segment, "]") {
+		return []pathStep{{key: segment}}
+	}
+
+	var steps []pathStep
+	if open > 0 {
+		steps = append(steps, pathStep{key: segment[:open]})
+	}
+	for _, index := range strings.Split(segment[open+1:len(segment)-1], "][") {
+		i, err := strconv.Atoi(index)
+		if err != nil {
+			return []pathStep{{key: segment}}
+		}
+		steps = append(steps, pathStep{index: i, isIndex: true})
+	}
+	return steps
+}
+
+// resolvePath walks nested objects and arrays one step at a time.
+func resolvePath(data map[string]interface{}, steps []pathStep, opts propertyOptions) (any, error) {
+	var current any = data
+	for _, step := range steps {
+		if step.isIndex {
+			list, ok := current.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%w: %w", ErrPropertyNotFound, ErrNotArray)
+			}
+			if step.index < 0 || step.index >= len(list) {
+				return nil, fmt.Errorf("%w: %w [%d] of %d elements", ErrPropertyNotFound, ErrIndexOutOfRange, step.index, len(list))
+			}
+			current = list[step.index]
+			continue
+		}
+
+		switch node := current.(type) {
+		case map[string]interface{}:
+			v, ok, err := opts.lookup(node, step.key)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, ErrPropertyNotFound
+			}
+			current = v
+		case []interface{}:
+			i, err := strconv.Atoi(step.key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, ErrPropertyNotFound
+			}
+			current = node[i]
+		default:
+			return nil, ErrPropertyNotFound
+		}
+	}
+	return current, nil
+}
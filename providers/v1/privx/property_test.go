@@ -0,0 +1,294 @@
+package privx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+func TestResolveProperty(t *testing.T) {
+	data := map[string]interface{}{
+		"user":    "admin",
+		"db.host": "literal",
+		"db": map[string]interface{}{
+			"host": "db.example.com",
+			"port": float64(5432),
+			"opts": map[string]interface{}{"ssl": true},
+		},
+		"a/b":   "slash",
+		"m~n":   "tilde",
+		"hosts": []interface{}{"a", "b"},
+		"empty": nil,
+		"servers": []interface{}{
+			map[string]interface{}{"name": "primary", "ports": []interface{}{float64(80), float64(443)}},
+		},
+		"matrix": []interface{}{[]interface{}{"x", "y"}},
+		"a[b]":   "bracket key",
+	}
+
+	tests := []struct {
+		name     string
+		property string
+		want     any
+		wantErr  error
+	}{
+		{name: "flat key", property: "user", want: "admin"},
+		{name: "literal key with dot wins", property: "db.host", want: "literal"},
+		{name: "dot path", property: "db.port", want: float64(5432)},
+		{name: "deep dot path", property: "db.opts.ssl", want: true},
+		{name: "dot path to object", property: "db.opts", want: map[string]interface{}{"ssl": true}},
+		{name: "json pointer", property: "/db/host", want: "db.example.com"},
+		{name: "json pointer escaped slash", property: "/a~1b", want: "slash"},
+		{name: "json pointer escaped tilde", property: "/m~0n", want: "tilde"},
+		{name: "json pointer array index", property: "/hosts/1", want: "b"},
+		{name: "empty property returns data", property: "", want: data},
+		{name: "missing key", property: "nope", wantErr: ErrPropertyNotFound},
+		{name: "missing nested key", property: "db.nope", wantErr: ErrPropertyNotFound},
+		{name: "path through scalar", property: "user.name", wantErr: ErrPropertyNotFound},
+		{name: "missing pointer", property: "/db/nope", wantErr: ErrPropertyNotFound},
+		{name: "null value", property: "empty", wantErr: ErrPropertyNotFound},
+		{name: "bracket index", property: "hosts[1]", want: "b"},
+		{name: "bracket index in path", property: "servers[0].name", want: "primary"},
+		{name: "nested bracket index", property: "servers[0].ports[1]", want: float64(443)},
+		{name: "consecutive bracket indices", property: "matrix[0][1]", want: "y"},
+		{name: "literal key with brackets", property: "a[b]", want: "bracket key"},
+		{name: "negative index", property: "hosts[-1]", wantErr: ErrIndexOutOfRange},
+		{name: "index out of range", property: "hosts[2]", wantErr: ErrIndexOutOfRange},
+		{name: "index out of range is not found", property: "hosts[2]", wantErr: ErrPropertyNotFound},
+		{name: "index on object", property: "servers[0][0]", wantErr: ErrNotArray},
+		{name: "index on scalar", property: "user[0]", wantErr: ErrNotArray},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := resolveProperty(data, tc.property, propertyOptions{})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("resolveProperty(%q) error = %v, want %v", tc.property, err, tc.wantErr)
+			}
+			if tc.wantErr == nil && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("resolveProperty(%q) = %v, want %v", tc.property, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetSecretPropertyList(t *testing.T) {
+	v := newFakeVault(fakeSecret("app", map[string]interface{}{
+		"user":     "admin",
+		"password": "s3cr3t",
+		"port":     float64(5432),
+		"a,b":      "literal",
+		"db":       map[string]interface{}{"host": "db.example.com"},
+	}))
+	c := newTestClient(v)
+
+	tests := []struct {
+		name        string
+		property    string
+		want        string
+		wantErr     error
+		wantMessage string
+	}{
+		{name: "single", property: "user", want: "admin"},
+		{name: "multiple", property: "user,password", want: `{"password":"s3cr3t","user":"admin"}`},
+		{name: "types and paths", property: "port, db.host", want: `{"db.host":"db.example.com","port":5432}`},
+		{name: "literal key with comma", property: "a,b", want: "literal"},
+		{name: "first missing", property: "user,nope,other", wantErr: ErrPropertyNotFound, wantMessage: "nope"},
+		{name: "empty element", property: "user,", wantErr: ErrPropertyNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "app", Property: tc.property})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("GetSecret(%q) error = %v, want %v", tc.property, err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				if !strings.Contains(err.Error(), tc.wantMessage) {
+					t.Errorf("GetSecret(%q) error = %v, want it to name %q", tc.property, err, tc.wantMessage)
+				}
+				return
+			}
+			if string(got) != tc.want {
+				t.Errorf("GetSecret(%q) = %s, want %s", tc.property, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetSecretCaseInsensitiveProperty(t *testing.T) {
+	v := newFakeVault(
+		fakeSecret("app", map[string]interface{}{
+			"Password": "s3cr3t",
+			"DB":       map[string]interface{}{"Host": "db.example.com"},
+		}),
+		fakeSecret("dup", map[string]interface{}{
+			"token": "a",
+			"TOKEN": "b",
+		}),
+	)
+
+	tests := []struct {
+		name            string
+		caseInsensitive bool
+		key             string
+		property        string
+		want            string
+		wantErr         error
+	}{
+		{name: "exact", key: "app", property: "Password", want: "s3cr3t"},
+		{name: "exact does not fold case", key: "app", property: "password", wantErr: ErrPropertyNotFound},
+		{name: "exact with duplicates", key: "dup", property: "TOKEN", want: "b"},
+		{name: "insensitive", caseInsensitive: true, key: "app", property: "password", want: "s3cr3t"},
+		{name: "insensitive path", caseInsensitive: true, key: "app", property: "db.host", want: "db.example.com"},
+		{name: "insensitive list", caseInsensitive: true, key: "app", property: "password,db.host", want: `{"db.host":"db.example.com","password":"s3cr3t"}`},
+		{name: "insensitive missing", caseInsensitive: true, key: "app", property: "user", wantErr: ErrPropertyNotFound},
+		{name: "ambiguous", caseInsensitive: true, key: "dup", property: "token", wantErr: ErrAmbiguousProperty},
+		{name: "ambiguous despite exact match", caseInsensitive: true, key: "dup", property: "TOKEN", wantErr: ErrAmbiguousProperty},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestClient(v)
+			c.properties = propertyOptions{caseInsensitive: tc.caseInsensitive}
+
+			got, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: tc.key, Property: tc.property})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("GetSecret(%q) error = %v, want %v", tc.property, err, tc.wantErr)
+			}
+			if tc.wantErr == nil && string(got) != tc.want {
+				t.Errorf("GetSecret(%q) = %s, want %s", tc.property, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetSecretMapCaseInsensitiveProperty(t *testing.T) {
+	v := newFakeVault(fakeSecret("app", map[string]interface{}{
+		"Creds": map[string]interface{}{"user": "admin"},
+		"creds": map[string]interface{}{"user": "root"},
+		"DB":    map[string]interface{}{"host": "db.example.com"},
+	}))
+	c := newTestClient(v)
+	c.properties = propertyOptions{caseInsensitive: true}
+
+	got, err := c.GetSecretMap(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "app", Property: "db"})
+	if err != nil {
+		t.Fatalf("GetSecretMap() error = %v", err)
+	}
+	if string(got["host"]) != "db.example.com" {
+		t.Errorf("GetSecretMap() = %v, want host", got)
+	}
+
+	_, err = c.GetSecretMap(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "app", Property: "CREDS"})
+	if !errors.Is(err, ErrAmbiguousProperty) {
+		t.Fatalf("GetSecretMap() error = %v, want %v", err, ErrAmbiguousProperty)
+	}
+	if !strings.Contains(err.Error(), "Creds, creds") {
+		t.Errorf("GetSecretMap() error = %v, want it to name both keys", err)
+	}
+}
+
+func TestPropertyMatch(t *testing.T) {
+	v := newFakeVault(fakeSecret("app", map[string]interface{}{
+		"db.host":    "literal.example.com",
+		"db":         map[string]interface{}{"host": "nested.example.com", "opts": map[string]interface{}{"ssl": true}},
+		"db.opts":    map[string]interface{}{"ssl": false},
+		"cache.host": "cache.example.com",
+	}))
+
+	tests := []struct {
+		name      string
+		pathFirst bool
+		property  string
+		want      string
+		wantMap   string
+	}{
+		{name: "literal first", property: "db.host", want: "literal.example.com"},
+		{name: "path first", pathFirst: true, property: "db.host", want: "nested.example.com"},
+		{name: "path first falls back to literal", pathFirst: true, property: "cache.host", want: "cache.example.com"},
+		{name: "path first pointer", pathFirst: true, property: "/db.host", want: "literal.example.com"},
+		{name: "literal first list", property: "db.host,cache.host", want: `{"cache.host":"cache.example.com","db.host":"literal.example.com"}`},
+		{name: "path first list", pathFirst: true, property: "db.host,cache.host", want: `{"cache.host":"cache.example.com","db.host":"nested.example.com"}`},
+		{name: "literal first map", property: "db.opts", wantMap: "false"},
+		{name: "path first map", pathFirst: true, property: "db.opts", wantMap: "true"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestClient(v)
+			c.properties = propertyOptions{pathFirst: tc.pathFirst}
+			ref := esv1.ExternalSecretDataRemoteRef{Key: "app", Property: tc.property}
+
+			if tc.wantMap != "" {
+				got, err := c.GetSecretMap(context.Background(), ref)
+				if err != nil {
+					t.Fatalf("GetSecretMap(%q) error = %v", tc.property, err)
+				}
+				if string(got["ssl"]) != tc.wantMap {
+					t.Errorf("GetSecretMap(%q) = %v, want ssl %s", tc.property, got, tc.wantMap)
+				}
+				return
+			}
+
+			got, err := c.GetSecret(context.Background(), ref)
+			if err != nil {
+				t.Fatalf("GetSecret(%q) error = %v", tc.property, err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("GetSecret(%q) = %s, want %s", tc.property, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetSecretJMESPath(t *testing.T) {
+	v := newFakeVault(fakeSecret("app", map[string]interface{}{
+		"servers": []interface{}{
+			map[string]interface{}{"host": "db1", "env": "prod", "port": float64(5432)},
+			map[string]interface{}{"host": "db2", "env": "test", "port": float64(5433)},
+			map[string]interface{}{"host": "db3", "env": "prod", "port": float64(5434)},
+		},
+		"jmespath:literal": "key",
+	}))
+	c := newTestClient(v)
+
+	tests := []struct {
+		name     string
+		property string
+		want     string
+		wantErr  error
+	}{
+		{name: "projection", property: "jmespath:servers[*].host", want: `["db1","db2","db3"]`},
+		{name: "filter", property: "jmespath:servers[?env=='prod'].host", want: `["db1","db3"]`},
+		{name: "scalar", property: "jmespath:servers[?host=='db2'] | [0].port", want: "5433"},
+		{name: "object", property: "jmespath:servers[0].{host: host, port: port}", want: `{"host":"db1","port":5432}`},
+		{name: "precedence over keys", property: "jmespath:literal", wantErr: ErrPropertyNotFound},
+		{name: "null result", property: "jmespath:missing", wantErr: ErrPropertyNotFound},
+		{name: "invalid", property: "jmespath:servers[?", wantErr: ErrInvalidJMESPath},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "app", Property: tc.property})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("GetSecret(%q) error = %v, want %v", tc.property, err, tc.wantErr)
+			}
+			if tc.wantErr == nil && string(got) != tc.want {
+				t.Errorf("GetSecret(%q) = %s, want %s", tc.property, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateStorePropertyMatch(t *testing.T) {
+	p := &Provider{}
+	_, err := p.ValidateStore(testStore(&esv1.PrivxProvider{Host: "https://privx.example.com", PropertyMatch: "exact"}))
+	if !errors.Is(err, ErrInvalidMatch) {
+		t.Errorf("ValidateStore() error = %v, want %v", err, ErrInvalidMatch)
+	}
+}
@@ -7,6 +7,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/SSHcom/privx-sdk-go/v2/api/vault"
@@ -15,6 +22,7 @@ import (
 	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
 	v1 "github.com/external-secrets/external-secrets/apis/meta/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	kclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -36,6 +44,19 @@ var (
 	ErrDecodeJWTPayload           = errors.New("failed to decode jwt payload")
 	ErrParseJWTPayload            = errors.New("failed to parse jwt payload json")
 	ErrServiceAccountNameNotFound = errors.New("serviceaccount name not found in jwt claims")
+	ErrMixedOAuthCredentials      = errors.New("conflicting OAuth credential references")
+	ErrNegativeValue              = errors.New("value must not be negative")
+	ErrNotPositive                = errors.New("value must be positive")
+	ErrInvalidBaseURL             = errors.New("invalid PrivX API base URL")
+	ErrPathNotRooted              = errors.New("path must start with /")
+	ErrBlankRole                  = errors.New("role must not be blank")
+	ErrInvalidRoleID              = errors.New("not a PrivX role ID")
+
+	// Classification of failures to connect and authenticate to PrivX.
+	ErrAuthFailed              = errors.New("PrivX authentication failed")
+	ErrCredentialSecretMissing = errors.New("PrivX credential secret missing")
+	ErrEmptyCredential         = errors.New("PrivX credential is empty")
+	ErrConnect                 = errors.New("cannot connect to PrivX")
 )
 
 type ErrNoStoreAuth struct {
@@ -54,27 +75,57 @@ var _ esv1.Provider = (*Provider)(nil)
 
 // Provider implements the ESO Provider interface for PrivX.
 type Provider struct {
+	// connectors are shared by the clients of stores, see connector.
+	// Nil disables sharing.
+	connectors *connectorCache
 }
 
-// readSecretValue gets a Kubernetes Secret as a string.
-func readSecretValue(
-	ctx context.Context,
-	client kclient.Client,
-	namespace string,
-	ref v1.SecretKeySelector,
-) (string, error) {
+// secretReader reads values from Kubernetes Secrets, getting every Secret only once.
+type secretReader struct {
+	kube      kclient.Client
+	storeKind string
+	namespace string
+	secrets   map[types.NamespacedName]*corev1.Secret
+}
+
+// newSecretReader creates a reader of the Secrets in namespace, or in the
+// namespaces the references name for a ClusterSecretStore.
+func newSecretReader(kube kclient.Client, storeKind, namespace string) *secretReader {
+	return &secretReader{
+		kube:      kube,
+		storeKind: storeKind,
+		namespace: namespace,
+		secrets:   map[types.NamespacedName]*corev1.Secret{},
+	}
+}
 
-	var secret corev1.Secret
-	if err := client.Get(ctx, types.NamespacedName{
-		Namespace: namespace,
+// value gets the value referenced by ref as a string.
+//
+// The namespace of ref applies to a ClusterSecretStore only, other stores read
+// the Secrets of their own namespace.
+func (r *secretReader) value(ctx context.Context, ref v1.SecretKeySelector) (string, error) {
+	name := types.NamespacedName{
+		Namespace: r.namespace,
 		Name:      ref.Name,
-	}, &secret); err != nil {
-		return "", err
+	}
+	if r.storeKind == esv1.ClusterSecretStoreKind && ref.Namespace != nil {
+		name.Namespace = *ref.Namespace
+	}
+	secret, ok := r.secrets[name]
+	if !ok {
+		secret = &corev1.Secret{}
+		if err := r.kube.Get(ctx, name, secret); err != nil {
+			if apierrors.IsNotFound(err) {
+				return "", fmt.Errorf("%w: %w", ErrCredentialSecretMissing, err)
+			}
+			return "", err
+		}
+		r.secrets[name] = secret
 	}
 
 	b, ok := secret.Data[ref.Key]
 	if !ok {
-		return "", fmt.Errorf("secret %s/%s missing key %q", namespace, ref.Name, ref.Key)
+		return "", fmt.Errorf("%w: secret %s missing key %q", ErrCredentialSecretMissing, name, ref.Key)
 	}
 
 	// logger := log.FromContext(ctx)
@@ -83,77 +134,128 @@ func readSecretValue(
 	return string(b), nil
 }
 
-// privxAuth creates authentication from information in the Store specification.
-func privxAuth(
+// readSecretValue gets a Kubernetes Secret as a string.
+func readSecretValue(
 	ctx context.Context,
-	kube kclient.Client,
+	client kclient.Client,
+	storeKind string,
 	namespace string,
-	privxSpec *esv1.PrivxProvider,
-) (privxapi.Authorizer, error) {
+	ref v1.SecretKeySelector,
+) (string, error) {
+	return newSecretReader(client, storeKind, namespace).value(ctx, ref)
+}
 
-	auth := privxapi.New(
-		privxapi.BaseURL(privxSpec.Host),
-	)
+// Conventional keys within the Secret referenced by PrivXOAuth.CredentialsSecretRef.
+const (
+	credentialsKeyClientID        = "clientID"
+	credentialsKeyClientSecret    = "clientSecret"
+	credentialsKeyAPIClientID     = "apiClientID"
+	credentialsKeyAPIClientSecret = "apiClientSecret"
+)
 
-	if privxSpec.Auth != nil &&
-		privxSpec.Auth.OAuth != nil {
-		// OAuth tokens given, use them
+// oauthCredentials holds the OAuth credential values read from Kubernetes Secrets.
+type oauthCredentials struct {
+	clientID        string // privx_api_oauth_client_id
+	clientSecret    string // privx_api_oauth_client_secret
+	apiClientID     string // privx_api_client_id
+	apiClientSecret string // privx_api_client_secret
+}
 
-		// apiClientIdRef:
-		// privx_api_client_id
+// checkNotEmpty returns ErrEmptyCredential naming the first empty credential.
+func (c *oauthCredentials) checkNotEmpty() error {
+	for _, credential := range []struct{ name, value string }{
+		{credentialsKeyClientID, c.clientID},
+		{credentialsKeyClientSecret, c.clientSecret},
+		{credentialsKeyAPIClientID, c.apiClientID},
+		{credentialsKeyAPIClientSecret, c.apiClientSecret},
+	} {
+		if credential.value == "" {
+			return fmt.Errorf("%w: %s", ErrEmptyCredential, credential.name)
+		}
+	}
+	return nil
+}
 
-		clientID, err := readSecretValue(
-			ctx,
-			kube,
-			namespace,
-			privxSpec.Auth.OAuth.ApiClientIDRef,
-		)
-		if err != nil {
-			return nil, err
+// oauthRefs returns the references to the OAuth credentials in the order
+// clientID, clientSecret, apiClientID, apiClientSecret.
+//
+// With CredentialsSecretRef all references point to the conventional keys of one Secret.
+func oauthRefs(o *esv1.PrivXOAuth) []v1.SecretKeySelector {
+	if o.CredentialsSecretRef == nil {
+		return []v1.SecretKeySelector{
+			o.ClientIDRef,
+			o.ClientSecretRef,
+			o.ApiClientIDRef,
+			o.ApiClientSecretRef,
 		}
+	}
 
-		// apiClientSecretRef:
-		// privx_api_client_secret
-		clientSecret, err := readSecretValue(
-			ctx,
-			kube,
-			namespace,
-			privxSpec.Auth.OAuth.ApiClientSecretRef,
-		)
-		if err != nil {
-			return nil, err
+	ref := func(key string) v1.SecretKeySelector {
+		return v1.SecretKeySelector{
+			Name:      o.CredentialsSecretRef.Name,
+			Namespace: o.CredentialsSecretRef.Namespace,
+			Key:       key,
 		}
+	}
+	return []v1.SecretKeySelector{
+		ref(credentialsKeyClientID),
+		ref(credentialsKeyClientSecret),
+		ref(credentialsKeyAPIClientID),
+		ref(credentialsKeyAPIClientSecret),
+	}
+}
 
-		// clientIdRef:
-		// privx_api_oauth_client_id
-		oAuthAccess, err := readSecretValue(
-			ctx,
-			kube,
-			namespace,
-			privxSpec.Auth.OAuth.ClientIDRef,
-		)
+// readOAuthCredentials reads the OAuth credentials from Kubernetes Secrets.
+//
+// A Secret referenced by several credentials is read once.
+func readOAuthCredentials(
+	ctx context.Context,
+	kube kclient.Client,
+	storeKind string,
+	namespace string,
+	o *esv1.PrivXOAuth,
+) (*oauthCredentials, error) {
+
+	reader := newSecretReader(kube, storeKind, namespace)
+	values := make([]string, 0, 4)
+	for _, ref := range oauthRefs(o) {
+		value, err := reader.value(ctx, ref)
 		if err != nil {
 			return nil, err
 		}
+		values = append(values, value)
+	}
 
-		// clientSecretRef:
-		// privx_api_oauth_client_secret
-		oAuthSecret, err := readSecretValue(
-			ctx,
-			kube,
-			namespace,
-			privxSpec.Auth.OAuth.ClientSecretRef,
-		)
+	return &oauthCredentials{
+		clientID:        values[0],
+		clientSecret:    values[1],
+		apiClientID:     values[2],
+		apiClientSecret: values[3],
+	}, nil
+}
+
+// privxAuth creates authentication from information in the Store specification.
+func privxAuth(
+	ctx context.Context,
+	kube kclient.Client,
+	storeKind string,
+	namespace string,
+	privxSpec *esv1.PrivxProvider,
+) (privxapi.Authorizer, error) {
+
+	if privxSpec.Auth != nil &&
+		privxSpec.Auth.OAuth != nil {
+		// OAuth tokens given, use them
+		creds, err := readOAuthCredentials(ctx, kube, storeKind, namespace, privxSpec.Auth.OAuth)
 		if err != nil {
 			return nil, err
 		}
+		// An empty value would only fail later, when requesting a token
+		if err := creds.checkNotEmpty(); err != nil {
+			return nil, err
+		}
 
-		return oauth.With(
-			auth,
-			oauth.Access(clientID),
-			oauth.Secret(clientSecret),
-			oauth.Digest(oAuthAccess, oAuthSecret),
-		), nil
+		return oauthAuthorizer(privxSpec, creds), nil
 	}
 
 	var token string
@@ -165,6 +267,7 @@ func privxAuth(
 		token, err = createSignedJWT(
 			ctx,
 			kube,
+			storeKind,
 			namespace,
 			privxSpec.Auth.JWTAuth.PublicKeyRef,
 			privxSpec.Auth.JWTAuth.Iss,
@@ -192,7 +295,7 @@ func privxAuth(
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrInvalidJWT, err)
 	}
-	logger := log.FromContext(ctx)
+	logger := log.FromContext(ctx).V(logDebug)
 	logger.Info("JWT token", "claims", decoded)
 
 	// Then exchange the token for a PrivX token
@@ -206,23 +309,135 @@ func privxAuth(
 	return oauth.WithToken("Bearer " + tokenResponse.AccessToken), nil
 }
 
+// oauthAuthorizer creates the authorizer of OAuth credentials.
+// The access token is requested on first use and renewed as it expires, or when
+// PrivX rejects it, see renewableAuthorizer.
+func oauthAuthorizer(privxSpec *esv1.PrivxProvider, creds *oauthCredentials) privxapi.Authorizer {
+	return newRenewableAuthorizer(func() privxapi.Authorizer {
+		auth := privxapi.New(
+			privxapi.BaseURL(privxSpec.Host),
+		)
+		return oauth.With(
+			withTokenPath(auth, privxSpec.AuthPath),
+			oauth.Access(creds.apiClientID),
+			oauth.Secret(creds.apiClientSecret),
+			oauth.Digest(creds.clientID, creds.clientSecret),
+		)
+	})
+}
+
+// renewableAuthorizer is an Authorizer whose cached access token can be dropped.
+// The SDK authorizers cannot forget their token, so renewToken replaces the
+// authorizer with a new one, which requests a token on first use.
+type renewableAuthorizer struct {
+	newAuth func() privxapi.Authorizer
+
+	mu   sync.Mutex
+	auth privxapi.Authorizer
+}
+
+func newRenewableAuthorizer(newAuth func() privxapi.Authorizer) *renewableAuthorizer {
+	return &renewableAuthorizer{newAuth: newAuth, auth: newAuth()}
+}
+
+// current returns the authorizer in use.
+func (a *renewableAuthorizer) current() privxapi.Authorizer {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.auth
+}
+
+func (a *renewableAuthorizer) AccessToken() (string, error) { return a.current().AccessToken() }
+func (a *renewableAuthorizer) Cookie() string               { return a.current().Cookie() }
+
+// CookieJar returns the cookie jar of the authorizer in use, if it has one.
+func (a *renewableAuthorizer) CookieJar() http.CookieJar {
+	if cjp, ok := a.current().(privxapi.CookieJarProvider); ok {
+		return cjp.CookieJar()
+	}
+	return nil
+}
+
+// renewToken drops the cached access token, see tokenRenewer.
+func (a *renewableAuthorizer) renewToken() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.auth = a.newAuth()
+}
+
 // privxAPI creates a working PrivX API connection from information in the Store specification.
 func privxAPI(
 	ctx context.Context,
 	kube kclient.Client,
+	storeKind string,
 	namespace string,
 	privxSpec *esv1.PrivxProvider,
-) (privxapi.Connector, error) {
+) (*connector, error) {
 
-	auth, err := privxAuth(ctx, kube, namespace, privxSpec)
+	auth, err := privxAuth(ctx, kube, storeKind, namespace, privxSpec)
 	if err != nil {
 		return nil, err
 	}
+	return apiConnector(privxSpec, auth)
+}
 
-	return privxapi.New(
-		privxapi.BaseURL(privxSpec.Host),
-		privxapi.Auth(auth),
-	), nil
+// apiConnector creates a PrivX API connection authenticating with auth.
+func apiConnector(privxSpec *esv1.PrivxProvider, auth privxapi.Authorizer) (*connector, error) {
+	baseURL, err := apiBaseURL(privxSpec)
+	if err != nil {
+		return nil, err
+	}
+	return newConnector(baseURL, auth, newTransport(privxSpec)), nil
+}
+
+// connector returns the PrivX API connection of a client.
+//
+// Stores authenticating with OAuth credentials share their connection through
+// the connector cache, see connectorCache, and shared is true. The tokens of the
+// other authentication methods are obtained per client, so their connections
+// are not shared.
+func (p *Provider) connector(
+	ctx context.Context,
+	kube kclient.Client,
+	store esv1.GenericStore,
+	namespace string,
+) (conn *connector, shared bool, err error) {
+
+	privxSpec := store.GetSpec().Provider.PrivX
+	if p.connectors == nil || privxSpec.Auth == nil || privxSpec.Auth.OAuth == nil {
+		conn, err = privxAPI(ctx, kube, store.GetKind(), namespace, privxSpec)
+		return conn, false, err
+	}
+
+	creds, err := readOAuthCredentials(ctx, kube, store.GetKind(), namespace, privxSpec.Auth.OAuth)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := creds.checkNotEmpty(); err != nil {
+		return nil, false, err
+	}
+
+	conn, err = p.connectors.get(storeIdentity(store, namespace), connectorKey(privxSpec, creds), func() (*connector, error) {
+		return apiConnector(privxSpec, oauthAuthorizer(privxSpec, creds))
+	})
+	return conn, err == nil, err
+}
+
+// apiBaseURL returns the base URL of PrivX API requests: Host, joined with
+// APIBasePath when set.
+func apiBaseURL(privxSpec *esv1.PrivxProvider) (string, error) {
+	if privxSpec.APIBasePath == "" {
+		return privxSpec.Host, nil
+	}
+
+	u, err := url.Parse(privxSpec.Host)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidBaseURL, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("%w: host %q is not an absolute URL", ErrInvalidBaseURL, privxSpec.Host)
+	}
+	return strings.TrimRight(u.JoinPath(privxSpec.APIBasePath).String(), "/"), nil
 }
 
 // NewClient returns a new PrivX Client.
@@ -234,23 +449,94 @@ func (p *Provider) NewClient(
 ) (esv1.SecretsClient, error) {
 
 	config := store.GetSpec().Provider.PrivX
-	conn, err := privxAPI(ctx, kube, namespace, config)
+
+	// Check the configuration before connecting, so that no connector is left open
+	var nameTemplate *template.Template
+	if config.NameTemplate != "" {
+		var err error
+		nameTemplate, err = parseNameTemplate(config.NameTemplate)
+		if err != nil {
+			return nil, err
+		}
+	}
+	keys, err := newKeyPolicy(config.AllowedKeys, config.DeniedKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, sharedConn, err := p.connector(ctx, kube, store, namespace)
 	if err != nil {
 		return nil, err
 	}
 
+	requestTimeout, findTimeout := timeouts(config)
+	maxFindResults, maxFindBytes := findLimits(config)
+	breaker := breakerFor(config)
+
+	if err := preflight(ctx, conn, config, requestTimeout); err != nil {
+		if !sharedConn {
+			conn.close()
+		}
+		return nil, err
+	}
+
 	client := SecretsClient{
-		conn:              conn,
-		vault:             vault.New(conn),
+		conn:       conn,
+		sharedConn: sharedConn,
+		vault:      withBreaker(openVault(conn, config), breaker),
+		newVault: func(ctx context.Context) vaultClient {
+			return withBreaker(openVault(conn.withContext(ctx), config), breaker)
+		},
+		requestTimeout:    requestTimeout,
+		findTimeout:       findTimeout,
+		keys:              keys,
+		verifyAfterWrite:  config.VerifyAfterWrite,
 		store:             store,
 		kube:              kube,
 		namespace:         namespace,
 		defaultReadRoles:  config.DefaultReadRoles,
 		defaultWriteRoles: config.DefaultWriteRoles,
+		nameTemplate:      nameTemplate,
+		findMetadataOnly:  config.FindMetadataOnly,
+		referenceCounting: config.ReferenceCounting,
+		properties:        newPropertyOptions(config),
+		managedOnly:       config.ManagedOnly,
+		transform:         newValueTransform(config.ValueTransform),
+		flattenSecretMap:  config.FlattenSecretMap,
+		maxSecretSize:     maxSecretSize(config),
+		keyNormalization:  config.KeyNormalization,
+		maxFindResults:    maxFindResults,
+		maxFindBytes:      maxFindBytes,
+
+		rejectObjectProperty: config.RejectObjectProperty,
+		requireProperty:      config.RequireProperty,
+
+		requireManagedByLabel: config.RequireManagedByLabel,
+		reads:                 conn.reads,
+		readScope:             string(config.VaultScope) + "/" + config.VaultUserID,
 	}
 	return &client, nil
 }
 
+// openVault returns the vault of the store scope on conn.
+func openVault(conn *connector, privxSpec *esv1.PrivxProvider) vaultClient {
+	v := scopedVault(vault.New(conn), privxSpec.VaultScope, privxSpec.VaultUserID)
+	return withRawData(v, conn, privxSpec.VaultScope, privxSpec.VaultUserID)
+}
+
+// timeouts returns the request and find timeouts of the store,
+// the find timeout defaulting to the request timeout.
+func timeouts(privxSpec *esv1.PrivxProvider) (request, find time.Duration) {
+	if privxSpec.RequestTimeout != nil {
+		request = privxSpec.RequestTimeout.Duration
+	}
+	find = request
+	if privxSpec.FindTimeout != nil {
+		find = privxSpec.FindTimeout.Duration
+	}
+	return request, find
+}
+
 func (p *Provider) ValidateStore(store esv1.GenericStore) (admission.Warnings, error) {
 
 	if store.GetSpec().Provider == nil {
@@ -272,7 +558,171 @@ func (p *Provider) ValidateStore(store esv1.GenericStore) (admission.Warnings, e
 		return nil, ErrNoStoreAuth{Field: "spec.provider.privx.host"}
 	}
 
-	return nil, nil
+	if _, err := apiBaseURL(privx); err != nil {
+		return nil, fmt.Errorf("spec.provider.privx.apiBasePath: %w", err)
+	}
+
+	if err := validatePaths(privx); err != nil {
+		return nil, err
+	}
+
+	if err := validateTransport(privx); err != nil {
+		return nil, err
+	}
+
+	if err := validateRoles("defaultReadRoles", privx.DefaultReadRoles); err != nil {
+		return nil, err
+	}
+	if err := validateRoles("defaultWriteRoles", privx.DefaultWriteRoles); err != nil {
+		return nil, err
+	}
+
+	if err := validateVaultScope(privx); err != nil {
+		return nil, fmt.Errorf("spec.provider.privx.vaultScope: %w", err)
+	}
+
+	if err := validatePropertyMatch(privx.PropertyMatch); err != nil {
+		return nil, fmt.Errorf("spec.provider.privx.propertyMatch: %w", err)
+	}
+
+	if err := validateKeyNormalization(privx.KeyNormalization); err != nil {
+		return nil, fmt.Errorf("spec.provider.privx.keyNormalization: %w", err)
+	}
+
+	if privx.NameTemplate != "" {
+		if _, err := parseNameTemplate(privx.NameTemplate); err != nil {
+			return nil, fmt.Errorf("spec.provider.privx.nameTemplate: %w", err)
+		}
+	}
+
+	if _, err := newKeyPolicy(privx.AllowedKeys, privx.DeniedKeys); err != nil {
+		return nil, fmt.Errorf("spec.provider.privx.%w", err)
+	}
+
+	if privx.Auth != nil && privx.Auth.OAuth != nil {
+		if err := validateOAuth(privx.Auth.OAuth); err != nil {
+			return nil, err
+		}
+	}
+
+	return deprecationWarnings(privx), nil
+}
+
+// validatePaths checks that the endpoint path overrides are rooted paths.
+func validatePaths(privx *esv1.PrivxProvider) error {
+	const field = "spec.provider.privx"
+
+	if privx.APIBasePath != "" && !strings.HasPrefix(privx.APIBasePath, "/") {
+		return fmt.Errorf("%s.apiBasePath: %w: %q", field, ErrPathNotRooted, privx.APIBasePath)
+	}
+	if privx.AuthPath != "" && !strings.HasPrefix(privx.AuthPath, "/") {
+		return fmt.Errorf("%s.authPath: %w: %q", field, ErrPathNotRooted, privx.AuthPath)
+	}
+	return nil
+}
+
+// roleIDPattern matches PrivX role IDs, which are UUIDs.
+var roleIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// validateRoles checks that the roles of the named field are PrivX role IDs,
+// catching blank entries and role names pasted in place of IDs.
+func validateRoles(field string, roles []string) error {
+	for i, role := range roles {
+		switch {
+		case strings.TrimSpace(role) == "":
+			return fmt.Errorf("spec.provider.privx.%s[%d]: %w", field, i, ErrBlankRole)
+		case !roleIDPattern.MatchString(role):
+			return fmt.Errorf("spec.provider.privx.%s[%d]: %w: %q", field, i, ErrInvalidRoleID, role)
+		}
+	}
+	return nil
+}
+
+// validateTransport checks that the HTTP transport settings are non-negative.
+func validateTransport(privx *esv1.PrivxProvider) error {
+	const field = "spec.provider.privx"
+
+	if privx.MaxIdleConns != nil && *privx.MaxIdleConns < 0 {
+		return fmt.Errorf("%s.maxIdleConns: %w", field, ErrNegativeValue)
+	}
+	if privx.MaxIdleConnsPerHost != nil && *privx.MaxIdleConnsPerHost < 0 {
+		return fmt.Errorf("%s.maxIdleConnsPerHost: %w", field, ErrNegativeValue)
+	}
+	if privx.IdleConnTimeout != nil && privx.IdleConnTimeout.Duration < 0 {
+		return fmt.Errorf("%s.idleConnTimeout: %w", field, ErrNegativeValue)
+	}
+	if privx.RequestTimeout != nil && privx.RequestTimeout.Duration < 0 {
+		return fmt.Errorf("%s.requestTimeout: %w", field, ErrNegativeValue)
+	}
+	if privx.FindTimeout != nil && privx.FindTimeout.Duration < 0 {
+		return fmt.Errorf("%s.findTimeout: %w", field, ErrNegativeValue)
+	}
+	if privx.MaxSecretSize != nil && *privx.MaxSecretSize < 1 {
+		return fmt.Errorf("%s.maxSecretSize: %w", field, ErrNotPositive)
+	}
+	if privx.MaxFindResults != nil && *privx.MaxFindResults < 1 {
+		return fmt.Errorf("%s.maxFindResults: %w", field, ErrNotPositive)
+	}
+	if privx.MaxFindBytes != nil && *privx.MaxFindBytes < 1 {
+		return fmt.Errorf("%s.maxFindBytes: %w", field, ErrNotPositive)
+	}
+	if b := privx.CircuitBreaker; b != nil {
+		if b.FailureThreshold != nil && *b.FailureThreshold < 1 {
+			return fmt.Errorf("%s.circuitBreaker.failureThreshold: %w", field, ErrNotPositive)
+		}
+		if b.Cooldown != nil && b.Cooldown.Duration < 0 {
+			return fmt.Errorf("%s.circuitBreaker.cooldown: %w", field, ErrNegativeValue)
+		}
+	}
+	return nil
+}
+
+// validateOAuth checks that OAuth credentials use either the single Secret
+// or the four explicit references, but not a mix of both.
+func validateOAuth(o *esv1.PrivXOAuth) error {
+	const field = "spec.provider.privx.auth.oauth"
+
+	explicit := map[string]v1.SecretKeySelector{
+		"clientIDRef":        o.ClientIDRef,
+		"clientSecretRef":    o.ClientSecretRef,
+		"apiClientIDRef":     o.ApiClientIDRef,
+		"apiClientSecretRef": o.ApiClientSecretRef,
+	}
+	var set, unset []string
+	for name, ref := range explicit {
+		if ref.Name != "" || ref.Key != "" {
+			set = append(set, name)
+		} else {
+			unset = append(unset, name)
+		}
+	}
+	sort.Strings(set)
+	sort.Strings(unset)
+
+	if o.CredentialsSecretRef != nil {
+		if len(set) > 0 {
+			return fmt.Errorf("%w: %s.credentialsSecretRef cannot be combined with %s",
+				ErrMixedOAuthCredentials, field, strings.Join(set, ", "))
+		}
+		if o.CredentialsSecretRef.Name == "" {
+			return ErrNoStoreAuth{Field: field + ".credentialsSecretRef.name"}
+		}
+		return nil
+	}
+
+	if len(unset) > 0 {
+		return ErrNoStoreAuth{Field: field + "." + unset[0]}
+	}
+	for _, name := range set {
+		ref := explicit[name]
+		if ref.Name == "" {
+			return ErrNoStoreAuth{Field: field + "." + name + ".name"}
+		}
+		if ref.Key == "" {
+			return ErrNoStoreAuth{Field: field + "." + name + ".key"}
+		}
+	}
+	return nil
 }
 
 func (p *Provider) Capabilities() esv1.SecretStoreCapabilities {
@@ -281,7 +731,7 @@ func (p *Provider) Capabilities() esv1.SecretStoreCapabilities {
 
 // NewProvider creates a new Provider instance.
 func NewProvider() esv1.Provider {
-	return &Provider{}
+	return &Provider{connectors: newConnectorCache(maxCachedConnectors)}
 }
 
 // ProviderSpec returns the provider specification for registration.
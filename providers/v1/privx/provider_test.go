@@ -0,0 +1,650 @@
+package privx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	v1 "github.com/external-secrets/external-secrets/apis/meta/v1"
+	testingfake "github.com/external-secrets/external-secrets/runtime/testing/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	kclient "sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func testStore(privx *esv1.PrivxProvider) *esv1.SecretStore {
+	return &esv1.SecretStore{
+		ObjectMeta: metav1.ObjectMeta{Name: "privx", Namespace: "default"},
+		Spec: esv1.SecretStoreSpec{
+			Provider: &esv1.SecretStoreProvider{PrivX: privx},
+		},
+	}
+}
+
+func kubeSecret(name string, data map[string]string) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Data:       map[string][]byte{},
+	}
+	for k, v := range data {
+		secret.Data[k] = []byte(v)
+	}
+	return secret
+}
+
+func explicitOAuth() *esv1.PrivXOAuth {
+	return &esv1.PrivXOAuth{
+		ClientIDRef:        v1.SecretKeySelector{Name: "privx-secret", Key: "privx_api_oauth_client_id"},
+		ClientSecretRef:    v1.SecretKeySelector{Name: "privx-secret", Key: "privx_api_oauth_client_secret"},
+		ApiClientIDRef:     v1.SecretKeySelector{Name: "privx-secret", Key: "privx_api_client_id"},
+		ApiClientSecretRef: v1.SecretKeySelector{Name: "privx-secret", Key: "privx_api_client_secret"},
+	}
+}
+
+func TestValidateStoreOAuth(t *testing.T) {
+	mixed := explicitOAuth()
+	mixed.CredentialsSecretRef = &esv1.PrivXCredentialsSecretRef{Name: "privx-credentials"}
+
+	partial := explicitOAuth()
+	partial.ApiClientSecretRef = v1.SecretKeySelector{}
+
+	tests := []struct {
+		name      string
+		oauth     *esv1.PrivXOAuth
+		wantErr   error
+		wantField string
+	}{
+		{name: "four references", oauth: explicitOAuth()},
+		{
+			name:  "single secret",
+			oauth: &esv1.PrivXOAuth{CredentialsSecretRef: &esv1.PrivXCredentialsSecretRef{Name: "privx-credentials"}},
+		},
+		{name: "mixed forms", oauth: mixed, wantErr: ErrMixedOAuthCredentials},
+		{
+			name:      "single secret without name",
+			oauth:     &esv1.PrivXOAuth{CredentialsSecretRef: &esv1.PrivXCredentialsSecretRef{}},
+			wantErr:   ErrNoStoreAuth{},
+			wantField: "credentialsSecretRef.name",
+		},
+		{name: "missing reference", oauth: partial, wantErr: ErrNoStoreAuth{}, wantField: "apiClientSecretRef"},
+		{name: "no credentials", oauth: &esv1.PrivXOAuth{}, wantErr: ErrNoStoreAuth{}, wantField: "apiClientIDRef"},
+	}
+
+	p := &Provider{}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := p.ValidateStore(testStore(&esv1.PrivxProvider{
+				Host: "https://privx.example.com",
+				Auth: &esv1.PrivXAuth{OAuth: tc.oauth},
+			}))
+			if tc.wantErr == nil {
+				if err != nil {
+					t.Fatalf("ValidateStore() unexpected error: %v", err)
+				}
+				return
+			}
+			var noAuth ErrNoStoreAuth
+			if errors.As(tc.wantErr, &noAuth) {
+				if !errors.As(err, &noAuth) {
+					t.Fatalf("ValidateStore() error = %v, want ErrNoStoreAuth", err)
+				}
+				if !strings.HasSuffix(noAuth.Field, tc.wantField) {
+					t.Errorf("ErrNoStoreAuth.Field = %q, want suffix %q", noAuth.Field, tc.wantField)
+				}
+				return
+			}
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ValidateStore() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadOAuthCredentials(t *testing.T) {
+	kube := clientfake.NewClientBuilder().WithObjects(
+		kubeSecret("privx-secret", map[string]string{
+			"privx_api_oauth_client_id":     "oauth-id",
+			"privx_api_oauth_client_secret": "oauth-secret",
+			"privx_api_client_id":           "api-id",
+			"privx_api_client_secret":       "api-secret",
+		}),
+		kubeSecret("privx-credentials", map[string]string{
+			"clientID":        "oauth-id",
+			"clientSecret":    "oauth-secret",
+			"apiClientID":     "api-id",
+			"apiClientSecret": "api-secret",
+		}),
+		kubeSecret("privx-incomplete", map[string]string{
+			"clientID":     "oauth-id",
+			"clientSecret": "oauth-secret",
+			"apiClientID":  "api-id",
+		}),
+	).Build()
+
+	want := oauthCredentials{
+		clientID:        "oauth-id",
+		clientSecret:    "oauth-secret",
+		apiClientID:     "api-id",
+		apiClientSecret: "api-secret",
+	}
+
+	tests := []struct {
+		name    string
+		oauth   *esv1.PrivXOAuth
+		wantErr string
+	}{
+		{name: "four references", oauth: explicitOAuth()},
+		{
+			name:  "single secret",
+			oauth: &esv1.PrivXOAuth{CredentialsSecretRef: &esv1.PrivXCredentialsSecretRef{Name: "privx-credentials"}},
+		},
+		{
+			name:    "single secret missing key",
+			oauth:   &esv1.PrivXOAuth{CredentialsSecretRef: &esv1.PrivXCredentialsSecretRef{Name: "privx-incomplete"}},
+			wantErr: `missing key "apiClientSecret"`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := readOAuthCredentials(context.Background(), kube, esv1.SecretStoreKind, "default", tc.oauth)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("readOAuthCredentials() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readOAuthCredentials() unexpected error: %v", err)
+			}
+			if *got != want {
+				t.Errorf("readOAuthCredentials() = %+v, want %+v", *got, want)
+			}
+		})
+	}
+}
+
+func TestReadOAuthCredentialsGets(t *testing.T) {
+	separate := &esv1.PrivXOAuth{
+		ClientIDRef:        v1.SecretKeySelector{Name: "privx-client-id", Key: "value"},
+		ClientSecretRef:    v1.SecretKeySelector{Name: "privx-client-secret", Key: "value"},
+		ApiClientIDRef:     v1.SecretKeySelector{Name: "privx-api-client-id", Key: "value"},
+		ApiClientSecretRef: v1.SecretKeySelector{Name: "privx-api-client-secret", Key: "value"},
+	}
+
+	tests := []struct {
+		name     string
+		oauth    *esv1.PrivXOAuth
+		wantGets int
+	}{
+		{name: "one secret", oauth: explicitOAuth(), wantGets: 1},
+		{
+			name:     "credentials secret",
+			oauth:    &esv1.PrivXOAuth{CredentialsSecretRef: &esv1.PrivXCredentialsSecretRef{Name: "privx-credentials"}},
+			wantGets: 1,
+		},
+		{name: "four secrets", oauth: separate, wantGets: 4},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gets := 0
+			kube := clientfake.NewClientBuilder().WithObjects(
+				kubeSecret("privx-secret", map[string]string{
+					"privx_api_oauth_client_id":     "oauth-id",
+					"privx_api_oauth_client_secret": "oauth-secret",
+					"privx_api_client_id":           "api-id",
+					"privx_api_client_secret":       "api-secret",
+				}),
+				kubeSecret("privx-credentials", map[string]string{
+					"clientID":        "oauth-id",
+					"clientSecret":    "oauth-secret",
+					"apiClientID":     "api-id",
+					"apiClientSecret": "api-secret",
+				}),
+				kubeSecret("privx-client-id", map[string]string{"value": "oauth-id"}),
+				kubeSecret("privx-client-secret", map[string]string{"value": "oauth-secret"}),
+				kubeSecret("privx-api-client-id", map[string]string{"value": "api-id"}),
+				kubeSecret("privx-api-client-secret", map[string]string{"value": "api-secret"}),
+			).WithInterceptorFuncs(interceptor.Funcs{
+				Get: func(ctx context.Context, c kclient.WithWatch, key kclient.ObjectKey, obj kclient.Object, opts ...kclient.GetOption) error {
+					gets++
+					return c.Get(ctx, key, obj, opts...)
+				},
+			}).Build()
+
+			if _, err := readOAuthCredentials(context.Background(), kube, esv1.SecretStoreKind, "default", tc.oauth); err != nil {
+				t.Fatalf("readOAuthCredentials() error = %v", err)
+			}
+			if gets != tc.wantGets {
+				t.Errorf("got %d Secret reads, want %d", gets, tc.wantGets)
+			}
+		})
+	}
+}
+
+func TestValidateStoreTransport(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    esv1.PrivxProvider
+		wantErr error
+	}{
+		{name: "unset", spec: esv1.PrivxProvider{}},
+		{
+			name: "valid",
+			spec: esv1.PrivxProvider{
+				MaxIdleConns:        ptr.To(0),
+				MaxIdleConnsPerHost: ptr.To(20),
+				IdleConnTimeout:     &metav1.Duration{Duration: time.Minute},
+			},
+		},
+		{name: "negative maxIdleConns", spec: esv1.PrivxProvider{MaxIdleConns: ptr.To(-1)}, wantErr: ErrNegativeValue},
+		{name: "negative maxIdleConnsPerHost", spec: esv1.PrivxProvider{MaxIdleConnsPerHost: ptr.To(-1)}, wantErr: ErrNegativeValue},
+		{
+			name:    "negative idleConnTimeout",
+			spec:    esv1.PrivxProvider{IdleConnTimeout: &metav1.Duration{Duration: -time.Second}},
+			wantErr: ErrNegativeValue,
+		},
+		{
+			name:    "negative requestTimeout",
+			spec:    esv1.PrivxProvider{RequestTimeout: &metav1.Duration{Duration: -time.Second}},
+			wantErr: ErrNegativeValue,
+		},
+		{
+			name:    "negative findTimeout",
+			spec:    esv1.PrivxProvider{FindTimeout: &metav1.Duration{Duration: -time.Second}},
+			wantErr: ErrNegativeValue,
+		},
+		{
+			name:    "zero maxSecretSize",
+			spec:    esv1.PrivxProvider{MaxSecretSize: ptr.To(0)},
+			wantErr: ErrNotPositive,
+		},
+		{
+			name:    "zero circuit breaker threshold",
+			spec:    esv1.PrivxProvider{CircuitBreaker: &esv1.PrivXCircuitBreaker{FailureThreshold: ptr.To(0)}},
+			wantErr: ErrNotPositive,
+		},
+		{
+			name:    "negative circuit breaker cooldown",
+			spec:    esv1.PrivxProvider{CircuitBreaker: &esv1.PrivXCircuitBreaker{Cooldown: &metav1.Duration{Duration: -time.Second}}},
+			wantErr: ErrNegativeValue,
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.spec.Host = "https://privx.example.com"
+			_, err := p.ValidateStore(testStore(&tc.spec))
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ValidateStore() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        esv1.PrivxProvider
+		wantRequest time.Duration
+		wantFind    time.Duration
+	}{
+		{name: "unset"},
+		{
+			name:        "find defaults to request",
+			spec:        esv1.PrivxProvider{RequestTimeout: &metav1.Duration{Duration: 5 * time.Second}},
+			wantRequest: 5 * time.Second,
+			wantFind:    5 * time.Second,
+		},
+		{
+			name: "separate find timeout",
+			spec: esv1.PrivxProvider{
+				RequestTimeout: &metav1.Duration{Duration: 5 * time.Second},
+				FindTimeout:    &metav1.Duration{Duration: 10 * time.Minute},
+			},
+			wantRequest: 5 * time.Second,
+			wantFind:    10 * time.Minute,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			request, find := timeouts(&tc.spec)
+			if request != tc.wantRequest || find != tc.wantFind {
+				t.Errorf("timeouts() = %v, %v, want %v, %v", request, find, tc.wantRequest, tc.wantFind)
+			}
+		})
+	}
+}
+
+func TestAuthErrorClassification(t *testing.T) {
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer unauthorized.Close()
+	unreachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	unreachable.Close()
+
+	kube := clientfake.NewClientBuilder().WithObjects(
+		kubeSecret("privx-credentials", map[string]string{"clientID": "oauth-id"}),
+	).Build()
+
+	tests := []struct {
+		name    string
+		run     func() error
+		wantErr error
+	}{
+		{
+			name: "missing credential secret",
+			run: func() error {
+				_, err := privxAuth(context.Background(), kube, esv1.SecretStoreKind, "default", &esv1.PrivxProvider{
+					Host: "https://privx.example.com",
+					Auth: &esv1.PrivXAuth{OAuth: explicitOAuth()},
+				})
+				return err
+			},
+			wantErr: ErrCredentialSecretMissing,
+		},
+		{
+			name: "missing credential key",
+			run: func() error {
+				_, err := privxAuth(context.Background(), kube, esv1.SecretStoreKind, "default", &esv1.PrivxProvider{
+					Host: "https://privx.example.com",
+					Auth: &esv1.PrivXAuth{OAuth: &esv1.PrivXOAuth{
+						CredentialsSecretRef: &esv1.PrivXCredentialsSecretRef{Name: "privx-credentials"},
+					}},
+				})
+				return err
+			},
+			wantErr: ErrCredentialSecretMissing,
+		},
+		{
+			name: "token rejected",
+			run: func() error {
+				_, err := ExchangeToken(context.Background(), nil, unauthorized.URL, ExchangeTokenRequest{Token: "jwt"})
+				return err
+			},
+			wantErr: ErrAuthFailed,
+		},
+		{
+			name: "token exchange unreachable",
+			run: func() error {
+				_, err := ExchangeToken(context.Background(), nil, unreachable.URL, ExchangeTokenRequest{Token: "jwt"})
+				return err
+			},
+			wantErr: ErrConnect,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.run(); !errors.Is(err, tc.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestAPIBaseURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     string
+		basePath string
+		want     string
+		wantErr  error
+	}{
+		{name: "default", host: "https://privx.example.com", want: "https://privx.example.com"},
+		{name: "base path", host: "https://privx.example.com", basePath: "/privx", want: "https://privx.example.com/privx"},
+		{name: "slashes", host: "https://privx.example.com/", basePath: "privx/v41/", want: "https://privx.example.com/privx/v41"},
+		{name: "relative host", host: "privx.example.com", basePath: "/privx", wantErr: ErrInvalidBaseURL},
+		{name: "invalid host", host: "https://privx example.com:port", basePath: "/privx", wantErr: ErrInvalidBaseURL},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := apiBaseURL(&esv1.PrivxProvider{Host: tc.host, APIBasePath: tc.basePath})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("apiBaseURL() error = %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("apiBaseURL() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateStorePaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    esv1.PrivxProvider
+		wantErr error
+	}{
+		{name: "unset"},
+		{name: "rooted", spec: esv1.PrivxProvider{APIBasePath: "/privx", AuthPath: "/privx/auth/api/v1/oauth/token"}},
+		{name: "relative apiBasePath", spec: esv1.PrivxProvider{APIBasePath: "privx"}, wantErr: ErrPathNotRooted},
+		{name: "relative authPath", spec: esv1.PrivxProvider{AuthPath: "auth/api/v1/oauth/token"}, wantErr: ErrPathNotRooted},
+	}
+
+	p := &Provider{}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.spec.Host = "https://privx.example.com"
+			_, err := p.ValidateStore(testStore(&tc.spec))
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ValidateStore() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestOAuthAuthPath(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"access_token":"token","expires_in":300}`)
+	}))
+	defer server.Close()
+
+	kube := clientfake.NewClientBuilder().WithObjects(
+		kubeSecret("privx-credentials", map[string]string{
+			"clientID":        "oauth-id",
+			"clientSecret":    "oauth-secret",
+			"apiClientID":     "api-id",
+			"apiClientSecret": "api-secret",
+		}),
+	).Build()
+
+	tests := []struct {
+		name     string
+		authPath string
+		want     string
+	}{
+		{name: "default", want: "/auth/api/v1/oauth/token"},
+		{name: "override", authPath: "/proxy/privx/token", want: "/proxy/privx/token"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			paths = nil
+			auth, err := privxAuth(context.Background(), kube, esv1.SecretStoreKind, "default", &esv1.PrivxProvider{
+				Host:     server.URL,
+				AuthPath: tc.authPath,
+				Auth: &esv1.PrivXAuth{OAuth: &esv1.PrivXOAuth{
+					CredentialsSecretRef: &esv1.PrivXCredentialsSecretRef{Name: "privx-credentials"},
+				}},
+			})
+			if err != nil {
+				t.Fatalf("privxAuth() error = %v", err)
+			}
+			token, err := auth.AccessToken()
+			if err != nil {
+				t.Fatalf("AccessToken() error = %v", err)
+			}
+			if token != "Bearer token" {
+				t.Errorf("AccessToken() = %q", token)
+			}
+			if len(paths) != 1 || paths[0] != tc.want {
+				t.Errorf("token requests = %v, want [%s]", paths, tc.want)
+			}
+		})
+	}
+}
+
+func TestCredentialNamespace(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth/api/v1/oauth/token":
+			_, _ = io.WriteString(w, `{"access_token":"token","expires_in":300}`)
+		case "/vault/api/v1/secrets":
+			authorization = r.Header.Get("Authorization")
+			_, _ = io.WriteString(w, `{"name":"app-secret"}`)
+		case "/vault/api/v1/secrets/app-secret":
+			// The push creates the secret
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"error_code":"SECRET_NOT_FOUND","error_message":"secret not found"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	credentials := kubeSecret("privx-credentials", map[string]string{
+		"clientID":        "oauth-id",
+		"clientSecret":    "oauth-secret",
+		"apiClientID":     "api-id",
+		"apiClientSecret": "api-secret",
+	})
+	credentials.Namespace = "privx-system"
+	kube := clientfake.NewClientBuilder().WithObjects(credentials).Build()
+
+	spec := esv1.SecretStoreSpec{Provider: &esv1.SecretStoreProvider{PrivX: &esv1.PrivxProvider{
+		Host: server.URL,
+		Auth: &esv1.PrivXAuth{OAuth: &esv1.PrivXOAuth{
+			CredentialsSecretRef: &esv1.PrivXCredentialsSecretRef{
+				Name:      "privx-credentials",
+				Namespace: ptr.To("privx-system"),
+			},
+		}},
+	}}}
+
+	tests := []struct {
+		name    string
+		store   esv1.GenericStore
+		wantErr error
+	}{
+		{
+			name:  "cluster store reads the configured namespace",
+			store: &esv1.ClusterSecretStore{ObjectMeta: metav1.ObjectMeta{Name: "privx"}, Spec: spec},
+		},
+		{
+			name:    "namespaced store reads its own namespace",
+			store:   &esv1.SecretStore{ObjectMeta: metav1.ObjectMeta{Name: "privx", Namespace: "apps"}, Spec: spec},
+			wantErr: ErrCredentialSecretMissing,
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			authorization = ""
+
+			// The push comes from a PushSecret in the apps namespace
+			client, err := p.NewClient(context.Background(), tc.store, kube, "apps")
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("NewClient() error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			defer client.Close(context.Background())
+
+			err = client.PushSecret(context.Background(), &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "source", Namespace: "apps"},
+				Data:       map[string][]byte{"password": []byte("s3cr3t")},
+			}, testingfake.PushSecretData{SecretKey: "password", RemoteKey: "app-secret"})
+			if err != nil {
+				t.Fatalf("PushSecret() error = %v", err)
+			}
+			if authorization != "Bearer token" {
+				t.Errorf("push Authorization = %q, want the token of the configured credentials", authorization)
+			}
+		})
+	}
+}
+
+func TestEmptyOAuthCredential(t *testing.T) {
+	for _, empty := range []string{"clientID", "clientSecret", "apiClientID", "apiClientSecret"} {
+		t.Run(empty, func(t *testing.T) {
+			values := map[string]string{
+				"clientID":        "oauth-id",
+				"clientSecret":    "oauth-secret",
+				"apiClientID":     "api-id",
+				"apiClientSecret": "api-secret",
+			}
+			values[empty] = ""
+			kube := clientfake.NewClientBuilder().WithObjects(kubeSecret("privx-credentials", values)).Build()
+
+			_, err := privxAuth(context.Background(), kube, esv1.SecretStoreKind, "default", &esv1.PrivxProvider{
+				Host: "https://privx.example.com",
+				Auth: &esv1.PrivXAuth{OAuth: &esv1.PrivXOAuth{
+					CredentialsSecretRef: &esv1.PrivXCredentialsSecretRef{Name: "privx-credentials"},
+				}},
+			})
+			if !errors.Is(err, ErrEmptyCredential) {
+				t.Fatalf("privxAuth() error = %v, want %v", err, ErrEmptyCredential)
+			}
+			if !strings.HasSuffix(err.Error(), ": "+empty) {
+				t.Errorf("privxAuth() error = %v, want it to name %s", err, empty)
+			}
+		})
+	}
+}
+
+func TestValidateStoreRoles(t *testing.T) {
+	const roleID = "0f6c3f44-5d0b-4e9d-a1f2-6c9b8f0a4e21"
+
+	tests := []struct {
+		name      string
+		read      []string
+		write     []string
+		wantErr   error
+		wantField string
+	}{
+		{name: "unset"},
+		{name: "role IDs", read: []string{roleID}, write: []string{roleID, strings.ToUpper(roleID)}},
+		{name: "empty read role", read: []string{roleID, ""}, wantErr: ErrBlankRole, wantField: "defaultReadRoles[1]"},
+		{name: "whitespace write role", write: []string{"  "}, wantErr: ErrBlankRole, wantField: "defaultWriteRoles[0]"},
+		{name: "role name", read: []string{"privx-admin"}, wantErr: ErrInvalidRoleID, wantField: "defaultReadRoles[0]"},
+		{name: "padded role ID", write: []string{" " + roleID}, wantErr: ErrInvalidRoleID, wantField: "defaultWriteRoles[0]"},
+	}
+
+	p := &Provider{}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := p.ValidateStore(testStore(&esv1.PrivxProvider{
+				Host:              "https://privx.example.com",
+				DefaultReadRoles:  tc.read,
+				DefaultWriteRoles: tc.write,
+			}))
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ValidateStore() error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil && !strings.Contains(err.Error(), "spec.provider.privx."+tc.wantField+":") {
+				t.Errorf("ValidateStore() error = %v, want it to name %s", err, tc.wantField)
+			}
+		})
+	}
+}
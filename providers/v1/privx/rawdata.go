@@ -0,0 +1,55 @@
+/*
+Read PrivX secrets whose data is not a JSON object.
+*/
+
+package privx
+
+import (
+	"encoding/json"
+	"errors"
+
+	privxapi "github.com/SSHcom/privx-sdk-go/v2/restapi"
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+var ErrNotObject = errors.New("secret data is not a JSON object")
+
+// secretDataGetter is implemented by vaults returning the secret data as is,
+// e.g. a JSON array, which the SDK cannot decode into vault.Secret.
+type secretDataGetter interface {
+	GetSecretData(secretName string) (json.RawMessage, error)
+}
+
+// isNotObject reports whether err is the SDK failing to decode secret data
+// that is not a JSON object.
+func isNotObject(err error) bool {
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &typeErr) && typeErr.Field == "data"
+}
+
+// rawDataVault adds secretDataGetter to a vault, reading the secret through api.
+type rawDataVault struct {
+	vaultClient
+	api  privxapi.Connector
+	path string
+	args []interface{}
+}
+
+// withRawData returns v reading raw secret data from the vault of scope.
+func withRawData(v vaultClient, api privxapi.Connector, scope esv1.PrivXVaultScope, userID string) vaultClient {
+	if scope == esv1.PrivXVaultScopePersonal {
+		return rawDataVault{vaultClient: v, api: api, path: "/vault/api/v1/user/%s/secrets/%s", args: []interface{}{userID}}
+	}
+	return rawDataVault{vaultClient: v, api: api, path: "/vault/api/v1/secrets/%s"}
+}
+
+func (v rawDataVault) GetSecretData(secretName string) (json.RawMessage, error) {
+	var secret struct {
+		Data json.RawMessage `json:"data"`
+	}
+	args := append(append([]interface{}{}, v.args...), secretName)
+	if _, err := v.api.URL(v.path, args...).Get(&secret); err != nil {
+		return nil, err
+	}
+	return secret.Data, nil
+}
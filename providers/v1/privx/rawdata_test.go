@@ -0,0 +1,90 @@
+package privx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+func TestGetSecretNonObjectData(t *testing.T) {
+	payloads := map[string]string{
+		"list":   `{"name":"list","data":["a",{"b":1}]}`,
+		"scalar": `{"name":"scalar","data":"s3cr3t"}`,
+		"object": `{"name":"object","data":{"password":"s3cr3t"}}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Shared and personal vault paths
+		name, ok := strings.CutPrefix(r.URL.Path, "/vault/api/v1/secrets/")
+		if !ok {
+			name, _ = strings.CutPrefix(r.URL.Path, "/vault/api/v1/user/user-1/secrets/")
+		}
+		payload, ok := payloads[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, payload)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		key      string
+		property string
+		want     string
+		wantErr  error
+	}{
+		{name: "array", key: "list", want: `["a",{"b":1}]`},
+		{name: "scalar", key: "scalar", want: `"s3cr3t"`},
+		{name: "object", key: "object", property: "password", want: "s3cr3t"},
+		{name: "property of array", key: "list", property: "0", wantErr: ErrNotObject},
+		{name: "property of scalar", key: "scalar", property: "password", wantErr: ErrNotObject},
+	}
+
+	for _, scope := range []esv1.PrivXVaultScope{esv1.PrivXVaultScopeShared, esv1.PrivXVaultScopePersonal} {
+		conn := newConnector(server.URL, staticAuth("Bearer token"), newTransport(&esv1.PrivxProvider{}))
+		c := &SecretsClient{vault: openVault(conn, &esv1.PrivxProvider{VaultScope: scope, VaultUserID: "user-1"})}
+
+		for _, tc := range tests {
+			t.Run(string(scope)+"/"+tc.name, func(t *testing.T) {
+				got, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: tc.key, Property: tc.property})
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("GetSecret() error = %v, want %v", err, tc.wantErr)
+				}
+				if tc.wantErr == nil && string(got) != tc.want {
+					t.Errorf("GetSecret() = %s, want %s", got, tc.want)
+				}
+			})
+		}
+	}
+}
+
+func TestGetSecretMapNonObjectData(t *testing.T) {
+	payloads := map[string]string{
+		"list":   `{"name":"list","data":["a",{"b":1}]}`,
+		"scalar": `{"name":"scalar","data":"s3cr3t"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, _ := strings.CutPrefix(r.URL.Path, "/vault/api/v1/secrets/")
+		_, _ = io.WriteString(w, payloads[name])
+	}))
+	defer server.Close()
+
+	conn := newConnector(server.URL, staticAuth("Bearer token"), newTransport(&esv1.PrivxProvider{}))
+	c := &SecretsClient{vault: openVault(conn, &esv1.PrivxProvider{})}
+
+	for _, key := range []string{"list", "scalar"} {
+		t.Run(key, func(t *testing.T) {
+			_, err := c.GetSecretMap(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: key})
+			if !errors.Is(err, ErrNotObject) || !strings.Contains(err.Error(), key) {
+				t.Errorf("GetSecretMap() error = %v, want %v naming the secret", err, ErrNotObject)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Track the pushes sharing a PrivX secret.
+*/
+
+package privx
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/vault"
+)
+
+var (
+	ErrConflict = errors.New("secret modified concurrently")
+)
+
+// refsKey is the data key listing the pushes into a secret when the store
+// enables reference counting. It is never returned by reads.
+const refsKey = "eso-refs"
+
+// secretRefs returns the references recorded in secret data.
+// ok is false for secrets pushed without reference counting.
+func secretRefs(data map[string]interface{}) (refs []string, ok bool) {
+	switch v := data[refsKey].(type) {
+	case []string:
+		return slices.Clone(v), true
+	case []interface{}:
+		for _, ref := range v {
+			if s, isString := ref.(string); isString {
+				refs = append(refs, s)
+			}
+		}
+		return refs, true
+	}
+	return nil, false
+}
+
+// isReservedKey reports whether a data key is reserved by the provider.
+func isReservedKey(key string) bool {
+	return key == refsKey || key == managedKey || key == fanOutKey
+}
+
+// visibleData returns secret data without the keys reserved by the provider.
+func visibleData(data map[string]interface{}) map[string]interface{} {
+	_, refs := data[refsKey]
+	_, managed := data[managedKey]
+	_, fanOut := data[fanOutKey]
+	if !refs && !managed && !fanOut {
+		return data
+	}
+	visible := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		if !isReservedKey(k) {
+			visible[k] = v
+		}
+	}
+	return visible
+}
+
+// addRef writes request into the secret it names and records ref as a user of it.
+//
+// A missing secret is created. An existing secret keeps its other data, roles and
+// owner; one without references is adopted as if ref had pushed it.
+//
+// With managedOnly a created secret is marked as managed, and an existing secret
+// without the marker is refused, see checkManaged.
+func (c *SecretsClient) addRef(request *vault.SecretRequest, ref string) error {
+	if c.managedOnly {
+		(*request.Data)[managedKey] = true
+	}
+
+	modify := func(existing *vault.Secret) (*vault.SecretRequest, error) {
+		if c.managedOnly {
+			if err := checkManaged(existing); err != nil {
+				return nil, err
+			}
+		}
+
+		data := map[string]interface{}{}
+		if existing.Data != nil {
+			for k, v := range *existing.Data {
+				data[k] = v
+			}
+		}
+		for k := range *request.Data {
+			// A value replacing a binary one must not stay marked as binary
+			delete(data, k+encodingSuffix)
+		}
+		for k, v := range *request.Data {
+			data[k] = v
+		}
+		refs, _ := secretRefs(data)
+		if !slices.Contains(refs, ref) {
+			refs = append(refs, ref)
+		}
+		data[refsKey] = refs
+
+		update := existing.SecretRequest
+		update.Data = &data
+		return &update, nil
+	}
+	return c.modifyOrCreate(request.Name, modify, func() error {
+		data := maps.Clone(*request.Data)
+		data[refsKey] = []string{ref}
+		create := *request
+		create.Data = &data
+		_, err := c.vault.CreateSecret(&create)
+		return err
+	})
+}
+
+// releaseRef removes ref and the data pushed under it from the named secret,
+// deleting the secret when no references remain.
+//
+// A secret without references is treated as having a single user and is deleted.
+func (c *SecretsClient) releaseRef(name, ref string) error {
+	return c.modifySecret(name, func(existing *vault.Secret) (*vault.SecretRequest, error) {
+		if existing.Data == nil {
+			return nil, c.vault.DeleteSecret(name)
+		}
+		refs, ok := secretRefs(*existing.Data)
+		if !ok {
+			return nil, c.vault.DeleteSecret(name)
+		}
+
+		refs = slices.DeleteFunc(refs, func(r string) bool { return r == ref })
+		if len(refs) == 0 {
+			return nil, c.vault.DeleteSecret(name)
+		}
+
+		data := map[string]interface{}{}
+		for k, v := range *existing.Data {
+			data[k] = v
+		}
+		if ref != "" {
+			delete(data, ref)
+			delete(data, ref+encodingSuffix)
+		}
+		data[refsKey] = refs
+
+		update := existing.SecretRequest
+		update.Data = &data
+		return &update, nil
+	})
+}
+
+// maxModifyAttempts bounds the read-modify-write cycles of modifySecret.
+const maxModifyAttempts = 3
+
+// modifySecret updates the named secret in a read-modify-write cycle.
+//
+// modify returns the update of the secret read, or nil when it handled the secret
+// itself. PrivX has no conditional writes, so the secret is checked to be unchanged
+// since it was read right before writing, and the cycle is repeated when another
+// writer changed it meanwhile. This narrows the window for lost updates but cannot
+// close it. ErrConflict is returned when every attempt saw a concurrent change.
+func (c *SecretsClient) modifySecret(name string, modify func(*vault.Secret) (*vault.SecretRequest, error)) error {
+	for attempt := 0; attempt < maxModifyAttempts; attempt++ {
+		existing, err := c.vault.GetSecret(name)
+		if err != nil {
+			return err
+		}
+		read := existing.Updated
+
+		update, err := modify(existing)
+		if err != nil || update == nil {
+			return err
+		}
+
+		current, err := c.vault.GetSecretsMetadata(name)
+		if err != nil {
+			return err
+		}
+		if !current.Updated.Equal(read) {
+			continue
+		}
+		return c.vault.UpdateSecret(name, update)
+	}
+	return fmt.Errorf("%w: secret %q after %d attempts", ErrConflict, name, maxModifyAttempts)
+}
+
+// modifyOrCreate updates the named secret through modify, see modifySecret, or
+// calls create when the secret is missing. A secret created by another writer
+// between the read and the create is updated instead.
+func (c *SecretsClient) modifyOrCreate(
+	name string,
+	modify func(*vault.Secret) (*vault.SecretRequest, error),
+	create func() error,
+) error {
+	for attempt := 0; attempt < maxModifyAttempts; attempt++ {
+		err := c.modifySecret(name, modify)
+		if err == nil || !isNotFound(err) {
+			return err
+		}
+		if err := create(); !isAlreadyExists(err) {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: secret %q after %d attempts", ErrConflict, name, maxModifyAttempts)
+}
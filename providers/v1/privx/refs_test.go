@@ -0,0 +1,219 @@
+package privx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/vault"
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/apis/externalsecrets/v1alpha1"
+	testingfake "github.com/external-secrets/external-secrets/runtime/testing/fake"
+)
+
+func TestReferenceCounting(t *testing.T) {
+	ctx := context.Background()
+	v := newFakeVault()
+	c := newTestClient(v)
+	c.referenceCounting = true
+
+	source := testSecret(map[string][]byte{"password": []byte("s3cr3t"), "token": []byte("t0k3n")})
+	for _, push := range []testingfake.PushSecretData{
+		{SecretKey: "password", RemoteKey: "shared", Property: "db"},
+		{SecretKey: "token", RemoteKey: "shared", Property: "api"},
+		{SecretKey: "token", RemoteKey: "shared", Property: "api"},
+	} {
+		if err := c.PushSecret(ctx, source, push); err != nil {
+			t.Fatalf("PushSecret(%s) error = %v", push.Property, err)
+		}
+	}
+
+	refs, _ := secretRefs(*v.secrets["shared"].Data)
+	if want := []string{"db", "api"}; !reflect.DeepEqual(refs, want) {
+		t.Fatalf("refs after push = %v, want %v", refs, want)
+	}
+	if len(v.created) != 1 || len(v.updated) != 2 {
+		t.Errorf("created %d and updated %d secrets, want 1 and 2", len(v.created), len(v.updated))
+	}
+
+	if err := c.DeleteSecret(ctx, v1alpha1.PushSecretRemoteRef{RemoteKey: "shared", Property: "db"}); err != nil {
+		t.Fatalf("DeleteSecret(db) error = %v", err)
+	}
+	shared, ok := v.secrets["shared"]
+	if !ok {
+		t.Fatal("secret deleted while still referenced")
+	}
+	if _, ok := (*shared.Data)["db"]; ok {
+		t.Error("released property still present")
+	}
+	if _, ok := (*shared.Data)["api"]; !ok {
+		t.Error("referenced property removed")
+	}
+
+	if err := c.DeleteSecret(ctx, v1alpha1.PushSecretRemoteRef{RemoteKey: "shared", Property: "api"}); err != nil {
+		t.Fatalf("DeleteSecret(api) error = %v", err)
+	}
+	if _, ok := v.secrets["shared"]; ok {
+		t.Error("secret kept after last reference was released")
+	}
+
+	// Deleting again is a no-op
+	if err := c.DeleteSecret(ctx, v1alpha1.PushSecretRemoteRef{RemoteKey: "shared", Property: "api"}); err != nil {
+		t.Fatalf("repeated DeleteSecret() error = %v", err)
+	}
+}
+
+func TestReferenceCountingLegacySecret(t *testing.T) {
+	ctx := context.Background()
+	v := newFakeVault(fakeSecret("legacy", map[string]interface{}{"password": "s3cr3t"}))
+	c := newTestClient(v)
+	c.referenceCounting = true
+
+	if err := c.DeleteSecret(ctx, v1alpha1.PushSecretRemoteRef{RemoteKey: "legacy", Property: "other"}); err != nil {
+		t.Fatalf("DeleteSecret() error = %v", err)
+	}
+	if _, ok := v.secrets["legacy"]; ok {
+		t.Error("secret without references was not deleted")
+	}
+}
+
+func TestReadsHideRefs(t *testing.T) {
+	v := newFakeVault(fakeSecret("shared", map[string]interface{}{
+		"api":   "t0k3n",
+		refsKey: []interface{}{"api"},
+	}))
+	c := newTestClient(v)
+
+	got, err := c.GetSecretMap(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "shared"})
+	if err != nil {
+		t.Fatalf("GetSecretMap() error = %v", err)
+	}
+	if _, ok := got[refsKey]; ok {
+		t.Errorf("GetSecretMap() returned %s", refsKey)
+	}
+
+	whole, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "shared"})
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if string(whole) != `{"api":"t0k3n"}` {
+		t.Errorf("GetSecret() = %s", whole)
+	}
+}
+
+func TestReferenceCountingConcurrentModification(t *testing.T) {
+	tests := []struct {
+		name      string
+		conflicts int
+		wantErr   error
+	}{
+		{name: "no conflict"},
+		{name: "one conflict then success", conflicts: 1},
+		{name: "conflicts exhaust attempts", conflicts: maxModifyAttempts, wantErr: ErrConflict},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			v := newFakeVault(fakeSecret("shared", map[string]interface{}{
+				"db":    "s3cr3t",
+				refsKey: []interface{}{"db"},
+			}))
+			c := newTestClient(v)
+			c.referenceCounting = true
+
+			conflicts := 0
+			v.onMetadata = func(name string) {
+				if conflicts < tc.conflicts {
+					// Another controller pushes between our read and write
+					conflicts++
+					other := map[string]interface{}{}
+					for k, val := range *v.secrets[name].Data {
+						other[k] = val
+					}
+					other[fmt.Sprintf("other-%d", conflicts)] = "value"
+					other[refsKey] = append(mustRefs(t, other), fmt.Sprintf("other-%d", conflicts))
+					if err := v.UpdateSecret(name, &vault.SecretRequest{Name: name, Data: &other}); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			err := c.PushSecret(ctx, testSecret(map[string][]byte{"token": []byte("t0k3n")}), testingfake.PushSecretData{
+				SecretKey: "token",
+				RemoteKey: "shared",
+				Property:  "api",
+			})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("PushSecret() error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+
+			// Neither our push nor the concurrent ones are lost
+			refs := mustRefs(t, *v.secrets["shared"].Data)
+			want := []string{"db"}
+			for i := 1; i <= tc.conflicts; i++ {
+				want = append(want, fmt.Sprintf("other-%d", i))
+			}
+			want = append(want, "api")
+			if !reflect.DeepEqual(refs, want) {
+				t.Errorf("refs = %v, want %v", refs, want)
+			}
+		})
+	}
+}
+
+// racingVault creates secret right after the first read misses it, as another
+// controller pushing the same secret would.
+type racingVault struct {
+	*fakeVault
+	secret vault.Secret
+	raced  bool
+}
+
+func (v *racingVault) GetSecret(secretName string) (*vault.Secret, error) {
+	if !v.raced {
+		v.raced = true
+		v.secrets[v.secret.Name] = &v.secret
+		return nil, errFakeNotFound
+	}
+	return v.fakeVault.GetSecret(secretName)
+}
+
+func TestReferenceCountingCreateRace(t *testing.T) {
+	v := newFakeVault()
+	c := newTestClient(v)
+	c.vault = &racingVault{fakeVault: v, secret: fakeSecret("shared", map[string]interface{}{
+		"db":    "s3cr3t",
+		refsKey: []interface{}{"db"},
+	})}
+	c.referenceCounting = true
+
+	err := c.PushSecret(context.Background(), testSecret(map[string][]byte{"token": []byte("t0k3n")}), testingfake.PushSecretData{
+		SecretKey: "token",
+		RemoteKey: "shared",
+		Property:  "api",
+	})
+	if err != nil {
+		t.Fatalf("PushSecret() error = %v", err)
+	}
+	if len(v.created) != 1 || len(v.updated) != 1 {
+		t.Errorf("created %d and updated %d secrets, want a refused create and an update", len(v.created), len(v.updated))
+	}
+	if got, want := mustRefs(t, *v.secrets["shared"].Data), []string{"db", "api"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("refs = %v, want %v", got, want)
+	}
+}
+
+func mustRefs(t *testing.T, data map[string]interface{}) []string {
+	t.Helper()
+	refs, ok := secretRefs(data)
+	if !ok {
+		t.Fatal("secret has no references")
+	}
+	return refs
+}
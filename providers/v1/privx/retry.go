@@ -0,0 +1,80 @@
+/*
+Retry PrivX requests failing transiently.
+*/
+
+package privx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Defaults for retrying transient failures.
+const (
+	defaultRetryAttempts = 4
+	defaultRetryBackoff  = 200 * time.Millisecond
+	maxRetryBackoff      = 5 * time.Second
+)
+
+// retryPolicy retries operations failing transiently, see do.
+// The zero value uses the defaults.
+type retryPolicy struct {
+	attempts int           // total attempts, including the first
+	backoff  time.Duration // delay before the first retry, doubled for each further retry
+}
+
+// do calls op until it succeeds, fails permanently, the attempts are exhausted or
+// ctx is done, sleeping with exponential backoff between attempts.
+//
+// The number of retries is recorded on the span of ctx and the last error is returned.
+func (p retryPolicy) do(ctx context.Context, op func() error) error {
+	attempts := p.attempts
+	if attempts <= 0 {
+		attempts = defaultRetryAttempts
+	}
+	backoff := p.backoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+
+	retries := 0
+	defer func() { trace.SpanFromContext(ctx).SetAttributes(attrRetries.Int(retries)) }()
+
+	for {
+		err := op()
+		if err == nil || !isTransient(err) || retries+1 >= attempts {
+			return err
+		}
+
+		log.FromContext(ctx).V(logDebug).Info("retrying PrivX request",
+			"attempt", retries+2, "backoff", backoff, "error", err.Error())
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		retries++
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+}
+
+// isTransient reports whether a failed request may succeed when retried:
+// PrivX could not be reached, or it responded 429 Too Many Requests or 5xx.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests ||
+			statusErr.StatusCode >= http.StatusInternalServerError
+	}
+	return errors.Is(err, ErrConnect)
+}
@@ -0,0 +1,98 @@
+package privx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/vault"
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/apis/externalsecrets/v1alpha1"
+)
+
+// flakyServer responds 503 Service Unavailable failures times, then with final.
+func flakyServer(t *testing.T, failures int, final http.HandlerFunc) (*httptest.Server, *int) {
+	t.Helper()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		final(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestSecretExistsRetries(t *testing.T) {
+	found := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"name":"app","data":{"password":"s3cr3t"}}`)
+	}
+	notFound := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"error_code":"SECRET_NOT_FOUND","error_message":"secret not found"}`)
+	}
+
+	tests := []struct {
+		name         string
+		failures     int
+		final        http.HandlerFunc
+		want         bool
+		wantErr      bool
+		wantRequests int
+	}{
+		{name: "503 then found", failures: 1, final: found, want: true, wantRequests: 2},
+		{name: "503 then not found", failures: 2, final: notFound, want: false, wantRequests: 3},
+		{name: "503 until exhausted", failures: 10, final: found, wantErr: true, wantRequests: 3},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server, requests := flakyServer(t, tc.failures, tc.final)
+			c := &SecretsClient{
+				vault: vault.New(newConnector(server.URL, staticAuth("Bearer token"), newTransport(&esv1.PrivxProvider{}))),
+				retry: retryPolicy{attempts: 3, backoff: time.Millisecond},
+			}
+
+			got, err := c.SecretExists(context.Background(), v1alpha1.PushSecretRemoteRef{RemoteKey: "app"})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("SecretExists() error = %v, want error %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("SecretExists() = %v, want %v", got, tc.want)
+			}
+			if *requests != tc.wantRequests {
+				t.Errorf("requests = %d, want %d", *requests, tc.wantRequests)
+			}
+		})
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "service unavailable", err: &StatusError{StatusCode: http.StatusServiceUnavailable, Err: errors.New("503")}, want: true},
+		{name: "too many requests", err: &StatusError{StatusCode: http.StatusTooManyRequests, Err: errors.New("429")}, want: true},
+		{name: "not found", err: &StatusError{StatusCode: http.StatusNotFound, Err: errFakeNotFound}, want: false},
+		{name: "connect", err: ErrConnect, want: true},
+		{name: "deadline", err: errors.Join(ErrConnect, context.DeadlineExceeded), want: false},
+		{name: "other", err: errors.New("boom"), want: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isTransient(tc.err); got != tc.want {
+				t.Errorf("isTransient(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Detect drift between the roles of a PrivX secret and the store defaults.
+*/
+
+package privx
+
+import (
+	"context"
+	"slices"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/rolestore"
+)
+
+// RoleDiff is the difference between the roles of a PrivX secret and the default
+// roles of the store. The role IDs are sorted.
+type RoleDiff struct {
+	// MissingRead are default read roles the secret lacks.
+	MissingRead []string
+
+	// ExtraRead are read roles of the secret that are not defaults.
+	ExtraRead []string
+
+	// MissingWrite are default write roles the secret lacks.
+	MissingWrite []string
+
+	// ExtraWrite are write roles of the secret that are not defaults.
+	ExtraWrite []string
+}
+
+// Drifted reports whether the roles of the secret differ from the defaults.
+func (d *RoleDiff) Drifted() bool {
+	return len(d.MissingRead)+len(d.ExtraRead)+len(d.MissingWrite)+len(d.ExtraWrite) > 0
+}
+
+// CheckRoles compares the roles of the secret pushed under remoteKey with the
+// default read and write roles of the store. Only the metadata of the secret is
+// fetched, never its data.
+//
+// remoteKey is resolved to the secret name like on deletion, see remoteRefName.
+func (c *SecretsClient) CheckRoles(ctx context.Context, remoteKey string) (_ *RoleDiff, err error) {
+	name, err := c.secretName(map[string]string{
+		nameVarNamespace: c.namespace,
+		nameVarRemoteKey: remoteKey,
+	}, remoteKey)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, ErrNoName
+	}
+	if err := c.keys.check(name); err != nil {
+		return nil, err
+	}
+
+	ctx, span := startSpan(ctx, "CheckRoles", name)
+	defer func() { endSpan(span, err) }()
+
+	c, cancel := c.withTimeout(ctx, c.requestTimeout)
+	defer cancel()
+
+	secret, err := c.vault.GetSecretsMetadata(name)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &RoleDiff{}
+	diff.MissingRead, diff.ExtraRead = diffRoles(c.defaultReadRoles, secret.ReadRoles)
+	diff.MissingWrite, diff.ExtraWrite = diffRoles(c.defaultWriteRoles, secret.WriteRoles)
+	return diff, nil
+}
+
+// diffRoles returns the role IDs of want missing from got, and those of got not in want.
+func diffRoles(want []string, got []rolestore.RoleHandle) (missing, extra []string) {
+	gotIDs := make([]string, 0, len(got))
+	for _, role := range got {
+		gotIDs = append(gotIDs, role.ID)
+	}
+	for _, id := range want {
+		if !slices.Contains(gotIDs, id) && !slices.Contains(missing, id) {
+			missing = append(missing, id)
+		}
+	}
+	for _, id := range gotIDs {
+		if !slices.Contains(want, id) && !slices.Contains(extra, id) {
+			extra = append(extra, id)
+		}
+	}
+	slices.Sort(missing)
+	slices.Sort(extra)
+	return missing, extra
+}
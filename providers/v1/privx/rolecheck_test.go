@@ -0,0 +1,72 @@
+package privx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/vault"
+)
+
+func TestCheckRoles(t *testing.T) {
+	withRoles := func(read, write []string) vault.Secret {
+		s := fakeSecret("app", map[string]interface{}{"password": "s3cr3t"})
+		s.ReadRoles = packRoles(read)
+		s.WriteRoles = packRoles(write)
+		return s
+	}
+
+	tests := []struct {
+		name    string
+		secret  vault.Secret
+		want    RoleDiff
+		drifted bool
+	}{
+		{
+			name:   "matching",
+			secret: withRoles([]string{"read-role"}, []string{"write-role"}),
+		},
+		{
+			name:    "missing role",
+			secret:  withRoles(nil, []string{"write-role"}),
+			want:    RoleDiff{MissingRead: []string{"read-role"}},
+			drifted: true,
+		},
+		{
+			name:    "extra role",
+			secret:  withRoles([]string{"read-role", "audit-role"}, []string{"write-role", "admin-role"}),
+			want:    RoleDiff{ExtraRead: []string{"audit-role"}, ExtraWrite: []string{"admin-role"}},
+			drifted: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := newFakeVault(tc.secret)
+			c := newTestClient(v)
+
+			got, err := c.CheckRoles(context.Background(), "app")
+			if err != nil {
+				t.Fatalf("CheckRoles() error = %v", err)
+			}
+			if !reflect.DeepEqual(*got, tc.want) {
+				t.Errorf("CheckRoles() = %+v, want %+v", *got, tc.want)
+			}
+			if got.Drifted() != tc.drifted {
+				t.Errorf("Drifted() = %v, want %v", got.Drifted(), tc.drifted)
+			}
+			if v.getCalls != 0 {
+				t.Errorf("CheckRoles() fetched the secret data %d times", v.getCalls)
+			}
+		})
+	}
+
+	c := newTestClient(newFakeVault())
+	if _, err := c.CheckRoles(context.Background(), "missing"); !isNotFound(err) {
+		t.Errorf("CheckRoles(missing) error = %v, want not found", err)
+	}
+	if _, err := c.CheckRoles(context.Background(), ""); !errors.Is(err, ErrNoName) {
+		t.Errorf("CheckRoles(\"\") error = %v, want %v", err, ErrNoName)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Check end to end that a PrivX store can write with its configured roles.
+*/
+
+package privx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/vault"
+)
+
+var ErrSelfTestFailed = errors.New("PrivX self-test failed")
+
+// Steps of SelfTest, in the order they run.
+const (
+	SelfTestCreate = "create"
+	SelfTestRead   = "read"
+	SelfTestDelete = "delete"
+)
+
+// selfTestPrefix starts the names of the probe secrets written by SelfTest.
+const selfTestPrefix = "eso-selftest-"
+
+// selfTestValue is stored in the probe secrets.
+const selfTestValue = "external-secrets self-test"
+
+// SelfTestStep is the outcome of a step of SelfTest.
+type SelfTestStep struct {
+	Name string
+	Err  error
+}
+
+// SelfTestReport describes a run of SelfTest.
+type SelfTestReport struct {
+	// SecretName is the name of the probe secret.
+	SecretName string
+
+	// Steps are the steps that ran, in order.
+	Steps []SelfTestStep
+}
+
+// Failed returns the first failed step, or nil when every step succeeded.
+func (r *SelfTestReport) Failed() *SelfTestStep {
+	for i := range r.Steps {
+		if r.Steps[i].Err != nil {
+			return &r.Steps[i]
+		}
+	}
+	return nil
+}
+
+// SelfTest creates a uniquely named probe secret with the default read and write
+// roles of the store, reads it back and deletes it again.
+//
+// The probe secret is deleted even when reading it fails, or when creating it
+// fails in a way that may have stored it after all. The returned error wraps
+// ErrSelfTestFailed and names the first failed step, which the report details.
+func (c *SecretsClient) SelfTest(ctx context.Context) (_ *SelfTestReport, err error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	name := selfTestPrefix + hex.EncodeToString(suffix)
+
+	ctx, span := startSpan(ctx, "SelfTest", name)
+	defer func() { endSpan(span, err) }()
+
+	c, cancel := c.withTimeout(ctx, c.requestTimeout)
+	defer cancel()
+
+	report := &SelfTestReport{SecretName: name}
+	if err := c.keys.check(name); err != nil {
+		report.Steps = append(report.Steps, SelfTestStep{Name: SelfTestCreate, Err: err})
+		return report, c.selfTestError(report)
+	}
+
+	_, createErr := c.vault.CreateSecret(&vault.SecretRequest{
+		Name:       name,
+		ReadRoles:  packRoles(c.defaultReadRoles),
+		WriteRoles: packRoles(c.defaultWriteRoles),
+		Data:       &map[string]interface{}{"probe": selfTestValue},
+	})
+	report.Steps = append(report.Steps, SelfTestStep{Name: SelfTestCreate, Err: createErr})
+
+	if createErr == nil {
+		report.Steps = append(report.Steps, SelfTestStep{Name: SelfTestRead, Err: c.selfTestRead(name)})
+	}
+
+	deleteErr := c.vault.DeleteSecret(name)
+	if createErr != nil && deleteErr != nil && isNotFound(deleteErr) {
+		// Nothing was created, so there is nothing to clean up
+		return report, c.selfTestError(report)
+	}
+	report.Steps = append(report.Steps, SelfTestStep{Name: SelfTestDelete, Err: deleteErr})
+
+	return report, c.selfTestError(report)
+}
+
+// selfTestRead checks that the probe secret reads back as written.
+func (c *SecretsClient) selfTestRead(name string) error {
+	secret, err := c.vault.GetSecret(name)
+	if err != nil {
+		return err
+	}
+	if secret.Data == nil || (*secret.Data)["probe"] != selfTestValue {
+		return fmt.Errorf("%w: %s", ErrSecretDataMissing, name)
+	}
+	return nil
+}
+
+// selfTestError returns the error of the first failed step of report, or nil.
+func (c *SecretsClient) selfTestError(report *SelfTestReport) error {
+	failed := report.Failed()
+	if failed == nil {
+		return nil
+	}
+	if isForbidden(failed.Err) {
+		return fmt.Errorf("%w: %s secret %q: %w: readRoles %v, writeRoles %v: %w",
+			ErrSelfTestFailed, failed.Name, report.SecretName, ErrRolesForbidden,
+			c.defaultReadRoles, c.defaultWriteRoles, failed.Err)
+	}
+	return fmt.Errorf("%w: %s secret %q: %w", ErrSelfTestFailed, failed.Name, report.SecretName, failed.Err)
+}
@@ -0,0 +1,98 @@
+package privx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	forbidden := &StatusError{StatusCode: http.StatusForbidden, Err: errors.New("error: FORBIDDEN")}
+	unavailable := &StatusError{StatusCode: http.StatusServiceUnavailable, Err: errors.New("unavailable")}
+
+	tests := []struct {
+		name      string
+		createErr error
+		getErr    error
+		wantSteps []string
+		wantErr   []error
+		wantFail  string
+	}{
+		{
+			name:      "success",
+			wantSteps: []string{SelfTestCreate, SelfTestRead, SelfTestDelete},
+		},
+		{
+			name:      "create forbidden",
+			createErr: forbidden,
+			wantSteps: []string{SelfTestCreate},
+			wantErr:   []error{ErrSelfTestFailed, ErrRolesForbidden},
+			wantFail:  SelfTestCreate,
+		},
+		{
+			name:      "read fails",
+			getErr:    unavailable,
+			wantSteps: []string{SelfTestCreate, SelfTestRead, SelfTestDelete},
+			wantErr:   []error{ErrSelfTestFailed, unavailable},
+			wantFail:  SelfTestRead,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := newFakeVault()
+			v.createErr = tc.createErr
+			v.getErr = tc.getErr
+			c := newTestClient(v)
+
+			report, err := c.SelfTest(context.Background())
+			for _, want := range tc.wantErr {
+				if !errors.Is(err, want) {
+					t.Errorf("SelfTest() error = %v, want %v", err, want)
+				}
+			}
+			if tc.wantErr == nil && err != nil {
+				t.Fatalf("SelfTest() error = %v", err)
+			}
+
+			if !strings.HasPrefix(report.SecretName, selfTestPrefix) {
+				t.Errorf("SecretName = %q, want prefix %q", report.SecretName, selfTestPrefix)
+			}
+			var steps []string
+			for _, step := range report.Steps {
+				steps = append(steps, step.Name)
+			}
+			if strings.Join(steps, ",") != strings.Join(tc.wantSteps, ",") {
+				t.Errorf("steps = %v, want %v", steps, tc.wantSteps)
+			}
+			if failed := report.Failed(); (failed == nil) != (tc.wantFail == "") || (failed != nil && failed.Name != tc.wantFail) {
+				t.Errorf("Failed() = %+v, want step %q", failed, tc.wantFail)
+			}
+
+			// The probe secret is cleaned up, and a failed create is cleaned up as well
+			if len(v.deleted) != 1 || v.deleted[0] != report.SecretName {
+				t.Errorf("deleted = %v, want [%s]", v.deleted, report.SecretName)
+			}
+			if len(v.secrets) != 0 {
+				t.Errorf("secrets left in vault: %d", len(v.secrets))
+			}
+		})
+	}
+}
+
+func TestSelfTestUniqueNames(t *testing.T) {
+	c := newTestClient(newFakeVault())
+	first, err := c.SelfTest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.SelfTest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.SecretName == second.SecretName {
+		t.Errorf("both self-tests used %q", first.SecretName)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Limit the size of the PrivX secrets read and pushed, and of find results.
+*/
+
+package privx
+
+import (
+	"errors"
+	"fmt"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+// defaultMaxSecretSize is the size limit of a Kubernetes Secret, used when the
+// store leaves MaxSecretSize unset.
+const defaultMaxSecretSize = 1 << 20
+
+// defaultMaxFindBytes bounds dataFrom.find when the store leaves MaxFindBytes
+// unset. The number of results is not limited by default, as the byte limit
+// already bounds the memory a find takes.
+const defaultMaxFindBytes = 16 << 20
+
+var (
+	ErrSecretTooLarge = errors.New("secret exceeds the maximum size")
+	ErrFindTooLarge   = errors.New("find result exceeds configured limit")
+)
+
+// maxSecretSize returns the secret size limit of the store.
+func maxSecretSize(privxSpec *esv1.PrivxProvider) int {
+	if privxSpec.MaxSecretSize != nil {
+		return *privxSpec.MaxSecretSize
+	}
+	return defaultMaxSecretSize
+}
+
+// findLimits returns the limits of the store on the number of secrets and the
+// total bytes returned by a find.
+func findLimits(privxSpec *esv1.PrivxProvider) (results, size int) {
+	size = defaultMaxFindBytes
+	if privxSpec.MaxFindResults != nil {
+		results = *privxSpec.MaxFindResults
+	}
+	if privxSpec.MaxFindBytes != nil {
+		size = *privxSpec.MaxFindBytes
+	}
+	return results, size
+}
+
+// checkFindResults fails with ErrFindTooLarge when a find already holding results
+// secrets is to add another beyond the limit of the store. A zero limit disables
+// the check.
+func (c *SecretsClient) checkFindResults(results int) error {
+	if c.maxFindResults > 0 && results >= c.maxFindResults {
+		return fmt.Errorf("%w: more than %d secrets match", ErrFindTooLarge, c.maxFindResults)
+	}
+	return nil
+}
+
+// checkFindSize fails with ErrFindTooLarge when size bytes of find results exceed
+// the limit of the store. A zero limit disables the check.
+func (c *SecretsClient) checkFindSize(size int) error {
+	if c.maxFindBytes > 0 && size > c.maxFindBytes {
+		return fmt.Errorf("%w: the matching secrets have more than %d bytes", ErrFindTooLarge, c.maxFindBytes)
+	}
+	return nil
+}
+
+// checkSize fails with ErrSecretTooLarge when size bytes of the named secret
+// exceed the limit of the store. A zero limit disables the check.
+func (c *SecretsClient) checkSize(name string, size int) error {
+	if c.maxSecretSize > 0 && size > c.maxSecretSize {
+		return fmt.Errorf("%w: secret %q has %d bytes, the limit is %d", ErrSecretTooLarge, name, size, c.maxSecretSize)
+	}
+	return nil
+}
+
+// checkReadSize checks the size of secret data read from PrivX, its JSON encoding.
+func (c *SecretsClient) checkReadSize(name string, data map[string]interface{}) error {
+	if c.maxSecretSize <= 0 {
+		return nil
+	}
+	b, err := marshalData(name, data)
+	if err != nil {
+		return err
+	}
+	return c.checkSize(name, len(b))
+}
+
+// checkPushSize checks the size of the values pushed to PrivX, the total length
+// of the pushed bytes.
+func (c *SecretsClient) checkPushSize(name string, values map[string]interface{}) error {
+	size := 0
+	for _, v := range values {
+		if b, ok := v.([]byte); ok {
+			size += len(b)
+		}
+	}
+	return c.checkSize(name, size)
+}
@@ -0,0 +1,149 @@
+package privx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/vault"
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	testingfake "github.com/external-secrets/external-secrets/runtime/testing/fake"
+)
+
+func TestMaxSecretSizeRead(t *testing.T) {
+	// The secret {"v":"x..."} encodes to the length of the value plus 8 bytes
+	tests := []struct {
+		name    string
+		limit   int
+		value   int
+		wantErr error
+	}{
+		{name: "under the limit", limit: 108, value: 99},
+		{name: "at the limit", limit: 108, value: 100},
+		{name: "over the limit", limit: 108, value: 101, wantErr: ErrSecretTooLarge},
+		{name: "under the default limit", value: defaultMaxSecretSize - 9},
+		{name: "at the default limit", value: defaultMaxSecretSize - 8},
+		{name: "over the default limit", value: defaultMaxSecretSize - 7, wantErr: ErrSecretTooLarge},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := newFakeVault(fakeSecret("app", map[string]interface{}{"v": strings.Repeat("x", tc.value)}))
+			c := newTestClient(v)
+			c.maxSecretSize = maxSecretSize(&esv1.PrivxProvider{})
+			if tc.limit > 0 {
+				c.maxSecretSize = tc.limit
+			}
+			ctx := context.Background()
+
+			if _, err := c.GetSecret(ctx, esv1.ExternalSecretDataRemoteRef{Key: "app"}); !errors.Is(err, tc.wantErr) {
+				t.Errorf("GetSecret() error = %v, want %v", err, tc.wantErr)
+			}
+			if _, err := c.GetSecret(ctx, esv1.ExternalSecretDataRemoteRef{Key: "app", Property: "v"}); !errors.Is(err, tc.wantErr) {
+				t.Errorf("GetSecret(property) error = %v, want %v", err, tc.wantErr)
+			}
+			if _, err := c.GetSecretMap(ctx, esv1.ExternalSecretDataRemoteRef{Key: "app"}); !errors.Is(err, tc.wantErr) {
+				t.Errorf("GetSecretMap() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaxSecretSizePush(t *testing.T) {
+	const limit = 100
+	tests := []struct {
+		name    string
+		value   int
+		wantErr error
+	}{
+		{name: "under the limit", value: 99},
+		{name: "at the limit", value: 100},
+		{name: "over the limit", value: 101, wantErr: ErrSecretTooLarge},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := newFakeVault()
+			c := newTestClient(v)
+			c.maxSecretSize = limit
+
+			err := c.PushSecret(context.Background(), testSecret(map[string][]byte{"password": []byte(strings.Repeat("x", tc.value))}),
+				testingfake.PushSecretData{SecretKey: "password", RemoteKey: "app-secret"})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("PushSecret() error = %v, want %v", err, tc.wantErr)
+			}
+			if wantCreated := tc.wantErr == nil; (len(v.created) == 1) != wantCreated {
+				t.Errorf("created %d secrets, want a create %v", len(v.created), wantCreated)
+			}
+		})
+	}
+}
+
+func TestSizeLimitDefaults(t *testing.T) {
+	spec := &esv1.PrivxProvider{}
+	if got := maxSecretSize(spec); got != defaultMaxSecretSize {
+		t.Errorf("maxSecretSize() = %d, want %d", got, defaultMaxSecretSize)
+	}
+	results, size := findLimits(spec)
+	if results != 0 || size != defaultMaxFindBytes {
+		t.Errorf("findLimits() = %d, %d, want no result limit and %d bytes", results, size, defaultMaxFindBytes)
+	}
+}
+
+func TestMaxFind(t *testing.T) {
+	// Each secret {"v":"xxxxxxxxxx"} encodes to 18 bytes
+	const secrets = 5
+	tests := []struct {
+		name         string
+		maxResults   int
+		maxBytes     int
+		metadataOnly bool
+		wantErr      error
+		wantGets     int
+	}{
+		{name: "unlimited", wantGets: secrets},
+		{name: "results at the limit", maxResults: secrets, wantGets: secrets},
+		{name: "results over the limit", maxResults: secrets - 2, wantErr: ErrFindTooLarge, wantGets: secrets - 2},
+		{name: "bytes at the limit", maxBytes: secrets * 18, wantGets: secrets},
+		{name: "bytes over the limit", maxBytes: 3*18 - 1, wantErr: ErrFindTooLarge, wantGets: 3},
+		{name: "metadata results over the limit", maxResults: 1, metadataOnly: true, wantErr: ErrFindTooLarge},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var list []vault.Secret
+			for i := range secrets {
+				list = append(list, fakeSecret(fmt.Sprintf("app-%d", i), map[string]interface{}{"v": strings.Repeat("x", 10)}))
+			}
+			v := newFakeVault(list...)
+			c := newTestClient(v)
+			c.maxFindResults = tc.maxResults
+			c.maxFindBytes = tc.maxBytes
+			c.findMetadataOnly = tc.metadataOnly
+
+			got, err := c.GetAllSecrets(context.Background(), esv1.ExternalSecretFind{ConversionStrategy: esv1.ExternalSecretConversionDefault})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("GetAllSecrets() error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr == nil && len(got) != secrets {
+				t.Errorf("GetAllSecrets() returned %d secrets, want %d", len(got), secrets)
+			}
+			if v.getCalls != tc.wantGets {
+				t.Errorf("GetAllSecrets() fetched %d secrets, want %d", v.getCalls, tc.wantGets)
+			}
+		})
+	}
+}
+
+func TestValidateStoreMaxFind(t *testing.T) {
+	zero := 0
+	p := &Provider{}
+	for _, spec := range []esv1.PrivxProvider{{MaxFindResults: &zero}, {MaxFindBytes: &zero}} {
+		spec.Host = "https://privx.example.com"
+		if _, err := p.ValidateStore(testStore(&spec)); !errors.Is(err, ErrNotPositive) {
+			t.Errorf("ValidateStore() error = %v, want %v", err, ErrNotPositive)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Trace PrivX operations with OpenTelemetry.
+*/
+
+package privx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/external-secrets/external-secrets/providers/v1/privx"
+
+// Span attribute keys. Secret keys are hashed and values are never recorded.
+const (
+	attrKeyHash = attribute.Key("privx.secret.key_hash")
+	attrOutcome = attribute.Key("privx.outcome")
+	attrRetries = attribute.Key("privx.retries")
+)
+
+// Span outcomes.
+const (
+	outcomeSuccess  = "success"
+	outcomeNotFound = "not_found"
+	outcomeError    = "error"
+)
+
+// startSpan starts a span for a PrivX operation as a child of the span in ctx.
+//
+// Spans are recorded with the global OpenTelemetry tracer provider and are
+// no-ops unless the process has registered one.
+func startSpan(ctx context.Context, operation, key string) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{attrRetries.Int(0)}
+	if key != "" {
+		attrs = append(attrs, attrKeyHash.String(hashKey(key)))
+	}
+	return otel.Tracer(tracerName).Start(ctx, "privx."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// endSpan records the outcome of the operation and ends the span.
+//
+// The error text is not recorded, as it may contain secret names.
+func endSpan(span trace.Span, err error) {
+	switch {
+	case err == nil:
+		span.SetAttributes(attrOutcome.String(outcomeSuccess))
+	case isNotFound(err):
+		span.SetAttributes(attrOutcome.String(outcomeNotFound))
+	default:
+		span.SetAttributes(attrOutcome.String(outcomeError))
+		span.SetStatus(codes.Error, outcomeError)
+	}
+	span.End()
+}
+
+// hashKey returns the hex encoded SHA-256 of a secret key.
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,136 @@
+package privx
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/vault"
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/apis/externalsecrets/v1alpha1"
+	testingfake "github.com/external-secrets/external-secrets/runtime/testing/fake"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a global tracer provider recording spans for the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[string]string {
+	attrs := map[string]string{}
+	for _, kv := range span.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	return attrs
+}
+
+func TestSpans(t *testing.T) {
+	recorder := recordSpans(t)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "reconcile")
+	v := newFakeVault(fakeSecret("app-secret", map[string]interface{}{"password": "s3cr3t"}))
+	c := newTestClient(v)
+
+	if _, err := c.GetSecret(ctx, esv1.ExternalSecretDataRemoteRef{Key: "app-secret", Property: "password"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetSecret(ctx, esv1.ExternalSecretDataRemoteRef{Key: "missing"}); err == nil {
+		t.Fatal("expected not found")
+	}
+	if err := c.PushSecret(ctx, testSecret(map[string][]byte{"token": []byte("t0k3n")}), testingfake.PushSecretData{
+		SecretKey: "token",
+		RemoteKey: "new-secret",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteSecret(ctx, v1alpha1.PushSecretRemoteRef{RemoteKey: "new-secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetAllSecrets(ctx, esv1.ExternalSecretFind{ConversionStrategy: esv1.ExternalSecretConversionDefault}); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	want := []struct {
+		name    string
+		key     string
+		outcome string
+	}{
+		{name: "privx.GetSecret", key: "app-secret", outcome: outcomeSuccess},
+		{name: "privx.GetSecret", key: "missing", outcome: outcomeNotFound},
+		{name: "privx.PushSecret", key: "new-secret", outcome: outcomeSuccess},
+		{name: "privx.DeleteSecret", key: "new-secret", outcome: outcomeSuccess},
+		{name: "privx.GetAllSecrets", outcome: outcomeSuccess},
+	}
+	if len(spans) != len(want)+1 {
+		t.Fatalf("got %d spans, want %d", len(spans), len(want)+1)
+	}
+
+	for i, w := range want {
+		span := spans[i]
+		if span.Name() != w.name {
+			t.Errorf("span %d name = %q, want %q", i, span.Name(), w.name)
+		}
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %s is not a child of the reconcile span", span.Name())
+		}
+		attrs := spanAttributes(span)
+		if attrs[string(attrOutcome)] != w.outcome {
+			t.Errorf("span %s outcome = %q, want %q", span.Name(), attrs[string(attrOutcome)], w.outcome)
+		}
+		if attrs[string(attrRetries)] != "0" {
+			t.Errorf("span %s retries = %q, want 0", span.Name(), attrs[string(attrRetries)])
+		}
+		if w.key != "" && attrs[string(attrKeyHash)] != hashKey(w.key) {
+			t.Errorf("span %s key hash = %q, want %q", span.Name(), attrs[string(attrKeyHash)], hashKey(w.key))
+		}
+		for k, v := range attrs {
+			for _, raw := range []string{"app-secret", "new-secret", "s3cr3t", "t0k3n"} {
+				if strings.Contains(v, raw) {
+					t.Errorf("span %s attribute %s leaks %q", span.Name(), k, raw)
+				}
+			}
+		}
+	}
+}
+
+func TestSpanRetries(t *testing.T) {
+	recorder := recordSpans(t)
+
+	server, _ := flakyServer(t, 2, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"name":"app-secret","data":{}}`)
+	})
+	c := &SecretsClient{
+		vault: vault.New(newConnector(server.URL, staticAuth("Bearer token"), newTransport(&esv1.PrivxProvider{}))),
+		retry: retryPolicy{attempts: 3, backoff: time.Millisecond},
+	}
+
+	exists, err := c.SecretExists(context.Background(), v1alpha1.PushSecretRemoteRef{RemoteKey: "app-secret"})
+	if err != nil || !exists {
+		t.Fatalf("SecretExists() = %v, %v, want true", exists, err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "privx.SecretExists" {
+		t.Fatalf("got spans %v, want a single privx.SecretExists span", spans)
+	}
+	attrs := spanAttributes(spans[0])
+	if attrs[string(attrRetries)] != "2" {
+		t.Errorf("retries = %q, want 2", attrs[string(attrRetries)])
+	}
+	if attrs[string(attrOutcome)] != outcomeSuccess {
+		t.Errorf("outcome = %q, want %q", attrs[string(attrOutcome)], outcomeSuccess)
+	}
+}
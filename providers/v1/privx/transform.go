@@ -0,0 +1,36 @@
+/*
+Transform values read from PrivX before returning them.
+*/
+
+package privx
+
+import (
+	"bytes"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+// valueTransform shapes the string values selected by GetSecret.
+// The zero value leaves values unchanged.
+type valueTransform struct {
+	trim        bool   // remove leading and trailing whitespace
+	stripPrefix string // remove this prefix when present, after trimming
+}
+
+func newValueTransform(spec *esv1.PrivXValueTransform) valueTransform {
+	if spec == nil {
+		return valueTransform{}
+	}
+	return valueTransform{trim: spec.Trim, stripPrefix: spec.StripPrefix}
+}
+
+// apply returns value trimmed and without the prefix, as configured.
+func (t valueTransform) apply(value []byte) []byte {
+	if t.trim {
+		value = bytes.TrimSpace(value)
+	}
+	if t.stripPrefix != "" {
+		value = bytes.TrimPrefix(value, []byte(t.stripPrefix))
+	}
+	return value
+}
@@ -0,0 +1,72 @@
+package privx
+
+import (
+	"context"
+	"testing"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+func TestGetSecretValueTransform(t *testing.T) {
+	v := newFakeVault(fakeSecret("app", map[string]interface{}{
+		"token":    "  Bearer t0k3n\n",
+		"bare":     "t0k3n",
+		"port":     float64(5432),
+		"settings": map[string]interface{}{"name": " spaced "},
+	}))
+
+	tests := []struct {
+		name      string
+		transform *esv1.PrivXValueTransform
+		property  string
+		want      string
+	}{
+		{name: "off by default", property: "token", want: "  Bearer t0k3n\n"},
+		{name: "trim", transform: &esv1.PrivXValueTransform{Trim: true}, property: "token", want: "Bearer t0k3n"},
+		{
+			name:      "trim and strip prefix",
+			transform: &esv1.PrivXValueTransform{Trim: true, StripPrefix: "Bearer "},
+			property:  "token",
+			want:      "t0k3n",
+		},
+		{
+			name:      "strip prefix without trim",
+			transform: &esv1.PrivXValueTransform{StripPrefix: "Bearer "},
+			property:  "token",
+			want:      "  Bearer t0k3n\n",
+		},
+		{
+			name:      "prefix absent",
+			transform: &esv1.PrivXValueTransform{Trim: true, StripPrefix: "Bearer "},
+			property:  "bare",
+			want:      "t0k3n",
+		},
+		{
+			name:      "non-string value",
+			transform: &esv1.PrivXValueTransform{Trim: true, StripPrefix: "54"},
+			property:  "port",
+			want:      "5432",
+		},
+		{
+			name:      "object value",
+			transform: &esv1.PrivXValueTransform{Trim: true},
+			property:  "settings",
+			want:      `{"name":" spaced "}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestClient(v)
+			c.transform = newValueTransform(tc.transform)
+
+			got, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "app", Property: tc.property})
+			if err != nil {
+				t.Fatalf("GetSecret() error = %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("GetSecret() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Route vault requests to the shared or a personal PrivX vault.
+*/
+
+package privx
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/SSHcom/privx-sdk-go/v2/api/filters"
+	"github.com/SSHcom/privx-sdk-go/v2/api/response"
+	"github.com/SSHcom/privx-sdk-go/v2/api/vault"
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+var (
+	ErrInvalidVaultScope = errors.New("invalid vault scope")
+	ErrNoVaultUserID     = errors.New("personal vault requires vaultUserID")
+)
+
+// personalVaultClient is the subset of the PrivX Vault API for personal vaults.
+type personalVaultClient interface {
+	GetUserSecret(userID, secretName string) (*vault.Secret, error)
+	GetUsersSecretsMetadata(userID, secretName string) (*vault.Secret, error)
+	GetUserSecrets(userID string, opts ...filters.Option) (*response.ResultSet[vault.Secret], error)
+	CreateUserSecret(userID string, secret *vault.SecretRequest) (vault.SecretCreate, error)
+	UpdateUserSecret(userID, secretName string, secret *vault.SecretRequest) error
+	DeleteUserSecret(userID, secretName string) error
+}
+
+// vaultAPI is the PrivX Vault API for both the shared and personal vaults.
+type vaultAPI interface {
+	vaultClient
+	personalVaultClient
+}
+
+// Check during compile that the SDK Vault satisfies vaultAPI
+var _ vaultAPI = (*vault.Vault)(nil)
+
+// validateVaultScope checks the vault scope and that a personal vault has a user.
+func validateVaultScope(privxSpec *esv1.PrivxProvider) error {
+	switch privxSpec.VaultScope {
+	case "", esv1.PrivXVaultScopeShared:
+		return nil
+	case esv1.PrivXVaultScopePersonal:
+		if privxSpec.VaultUserID == "" {
+			return ErrNoVaultUserID
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidVaultScope, privxSpec.VaultScope)
+	}
+}
+
+// scopedVault returns the vault of the store scope, the shared vault by default.
+func scopedVault(api vaultAPI, scope esv1.PrivXVaultScope, userID string) vaultClient {
+	if scope == esv1.PrivXVaultScopePersonal {
+		return personalVault{api: api, userID: userID}
+	}
+	return api
+}
+
+// personalVault is the personal vault of a PrivX user.
+type personalVault struct {
+	api    personalVaultClient
+	userID string
+}
+
+func (p personalVault) GetSecret(secretName string) (*vault.Secret, error) {
+	return p.api.GetUserSecret(p.userID, secretName)
+}
+
+func (p personalVault) GetSecretsMetadata(secretName string) (*vault.Secret, error) {
+	return p.api.GetUsersSecretsMetadata(p.userID, secretName)
+}
+
+func (p personalVault) GetSecrets(opts ...filters.Option) (*response.ResultSet[vault.Secret], error) {
+	return p.api.GetUserSecrets(p.userID, opts...)
+}
+
+func (p personalVault) CreateSecret(secret *vault.SecretRequest) (vault.SecretCreate, error) {
+	return p.api.CreateUserSecret(p.userID, secret)
+}
+
+func (p personalVault) UpdateSecret(secretName string, secret *vault.SecretRequest) error {
+	return p.api.UpdateUserSecret(p.userID, secretName, secret)
+}
+
+func (p personalVault) DeleteSecret(secretName string) error {
+	return p.api.DeleteUserSecret(p.userID, secretName)
+}
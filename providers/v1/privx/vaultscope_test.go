@@ -0,0 +1,139 @@
+package privx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	testingfake "github.com/external-secrets/external-secrets/runtime/testing/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestVaultScope(t *testing.T) {
+	tests := []struct {
+		name         string
+		scope        esv1.PrivXVaultScope
+		wantPassword string
+		wantPersonal bool
+	}{
+		{name: "default", wantPassword: "shared-password"},
+		{name: "shared", scope: esv1.PrivXVaultScopeShared, wantPassword: "shared-password"},
+		{name: "personal", scope: esv1.PrivXVaultScopePersonal, wantPassword: "personal-password", wantPersonal: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			api := newFakeVault(fakeSecret("app", map[string]interface{}{"password": "shared-password"}))
+			personal := api.user("user-1")
+			personal.secrets["app"] = ptr.To(fakeSecret("app", map[string]interface{}{"password": "personal-password"}))
+
+			c := newTestClient(api)
+			c.vault = scopedVault(api, tc.scope, "user-1")
+
+			got, err := c.GetSecret(ctx, esv1.ExternalSecretDataRemoteRef{Key: "app", Property: "password"})
+			if err != nil {
+				t.Fatalf("GetSecret() error = %v", err)
+			}
+			if string(got) != tc.wantPassword {
+				t.Errorf("GetSecret() = %q, want %q", got, tc.wantPassword)
+			}
+
+			if err := c.PushSecret(ctx, testSecret(map[string][]byte{"token": []byte("t0k3n")}), testingfake.PushSecretData{
+				SecretKey: "token",
+				RemoteKey: "pushed",
+			}); err != nil {
+				t.Fatalf("PushSecret() error = %v", err)
+			}
+			_, inPersonal := personal.secrets["pushed"]
+			_, inShared := api.secrets["pushed"]
+			if inPersonal != tc.wantPersonal || inShared == tc.wantPersonal {
+				t.Errorf("pushed to personal vault = %v, shared vault = %v", inPersonal, inShared)
+			}
+
+			all, err := c.GetAllSecrets(ctx, esv1.ExternalSecretFind{ConversionStrategy: esv1.ExternalSecretConversionDefault})
+			if err != nil {
+				t.Fatalf("GetAllSecrets() error = %v", err)
+			}
+			if len(all) != 2 {
+				t.Errorf("GetAllSecrets() returned %d secrets, want 2", len(all))
+			}
+		})
+	}
+}
+
+func TestValidateVaultScope(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    esv1.PrivxProvider
+		wantErr error
+	}{
+		{name: "unset"},
+		{name: "shared", spec: esv1.PrivxProvider{VaultScope: esv1.PrivXVaultScopeShared}},
+		{name: "personal", spec: esv1.PrivxProvider{VaultScope: esv1.PrivXVaultScopePersonal, VaultUserID: "user-1"}},
+		{name: "personal without user", spec: esv1.PrivxProvider{VaultScope: esv1.PrivXVaultScopePersonal}, wantErr: ErrNoVaultUserID},
+		{name: "unknown", spec: esv1.PrivxProvider{VaultScope: "team"}, wantErr: ErrInvalidVaultScope},
+	}
+
+	p := &Provider{}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.spec.Host = "https://privx.example.com"
+			_, err := p.ValidateStore(testStore(&tc.spec))
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ValidateStore() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetAllSecretsSameNameInOtherVault(t *testing.T) {
+	for _, scope := range []esv1.PrivXVaultScope{esv1.PrivXVaultScopeShared, esv1.PrivXVaultScopePersonal} {
+		api := newFakeVault(fakeSecret("app", map[string]interface{}{"vault": "shared"}))
+		personal := api.user("user-1")
+		personal.secrets["app"] = ptr.To(fakeSecret("app", map[string]interface{}{"vault": "personal"}))
+
+		c := newTestClient(api)
+		c.vault = scopedVault(api, scope, "user-1")
+
+		got, err := c.GetAllSecrets(context.Background(), esv1.ExternalSecretFind{ConversionStrategy: esv1.ExternalSecretConversionDefault})
+		if err != nil {
+			t.Fatalf("%s: GetAllSecrets() error = %v", scope, err)
+		}
+		if want := `{"vault":"` + string(scope) + `"}`; string(got["app"]) != want {
+			t.Errorf("%s: GetAllSecrets() app = %s, want %s", scope, got["app"], want)
+		}
+	}
+}
+
+func TestGetSecretKeyIsName(t *testing.T) {
+	v := newFakeVault(
+		fakeSecret("app", map[string]interface{}{"password": "s3cr3t"}),
+		fakeSecret("id:app", map[string]interface{}{"password": "by name"}),
+	)
+	c := newTestClient(v)
+
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{key: "app", want: "s3cr3t"},
+		{key: "id:app", want: "by name"},
+		{key: "id:0f6c3f44-5d0b-4e9d-a1f2-6c9b8f0a4e21", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		got, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: tc.key, Property: "password"})
+		if tc.wantErr {
+			if !isNotFound(err) {
+				t.Errorf("GetSecret(%q) error = %v, want not found", tc.key, err)
+			}
+			continue
+		}
+		if err != nil || string(got) != tc.want {
+			t.Errorf("GetSecret(%q) = %s, %v, want %s", tc.key, got, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+/*
+Derive a version of PrivX secret values for change detection.
+*/
+
+package privx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+// versionPrefix names the hash function of the versions returned by GetSecretVersion.
+const versionPrefix = "sha256:"
+
+// GetSecretVersion returns a version of the value GetSecret returns for ref, so
+// that callers can tell whether it changed since an earlier read.
+//
+// PrivX secrets carry no version or etag, and their update time also changes when
+// only their roles do, so the version is the SHA-256 of the value. It changes if
+// and only if the value does: keys of the secret that ref does not select, and
+// rewrites of unchanged data, leave it as is.
+func (c *SecretsClient) GetSecretVersion(ctx context.Context, ref esv1.ExternalSecretDataRemoteRef) (string, error) {
+	value, err := c.GetSecret(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	return valueVersion(value), nil
+}
+
+// valueVersion returns the version of a secret value.
+func valueVersion(value []byte) string {
+	sum := sha256.Sum256(value)
+	return versionPrefix + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,70 @@
+package privx
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+func TestGetSecretVersion(t *testing.T) {
+	ctx := context.Background()
+	v := newFakeVault(fakeSecret("app", map[string]interface{}{"password": "s3cr3t", "user": "admin"}))
+	c := newTestClient(v)
+	whole := esv1.ExternalSecretDataRemoteRef{Key: "app"}
+	password := esv1.ExternalSecretDataRemoteRef{Key: "app", Property: "password"}
+
+	version := func(ref esv1.ExternalSecretDataRemoteRef) string {
+		t.Helper()
+		got, err := c.GetSecretVersion(ctx, ref)
+		if err != nil {
+			t.Fatalf("GetSecretVersion() error = %v", err)
+		}
+		return got
+	}
+	set := func(key string, value interface{}) {
+		(*v.secrets["app"].Data)[key] = value
+		v.secrets["app"].Updated = v.secrets["app"].Updated.Add(time.Second)
+	}
+
+	wholeV, passwordV := version(whole), version(password)
+	if !strings.HasPrefix(wholeV, versionPrefix) || wholeV == passwordV {
+		t.Fatalf("versions %q and %q, want distinct SHA-256 versions", wholeV, passwordV)
+	}
+
+	tests := []struct {
+		name         string
+		key          string
+		value        interface{}
+		wholeChanged bool
+		passChanged  bool
+	}{
+		{name: "rewritten unchanged", key: "password", value: "s3cr3t"},
+		{name: "other key changed", key: "user", value: "root", wholeChanged: true},
+		{name: "selected key changed", key: "password", value: "n3w", wholeChanged: true, passChanged: true},
+		{name: "changed back", key: "password", value: "s3cr3t", wholeChanged: true, passChanged: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			set(tc.key, tc.value)
+			gotWhole, gotPassword := version(whole), version(password)
+			if changed := gotWhole != wholeV; changed != tc.wholeChanged {
+				t.Errorf("whole secret version changed = %v, want %v", changed, tc.wholeChanged)
+			}
+			if changed := gotPassword != passwordV; changed != tc.passChanged {
+				t.Errorf("property version changed = %v, want %v", changed, tc.passChanged)
+			}
+			wholeV, passwordV = gotWhole, gotPassword
+		})
+	}
+}
+
+func TestGetSecretVersionNotFound(t *testing.T) {
+	c := newTestClient(newFakeVault())
+	if _, err := c.GetSecretVersion(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "missing"}); err == nil {
+		t.Error("GetSecretVersion() of a missing secret succeeded")
+	}
+}